@@ -1,21 +1,26 @@
 package main
 
 import (
+	"flag"
 	"gg-lang/src/schemes"
-	"os"
 )
 
 func main() {
-	if len(os.Args) < 2 {
-		schemes.Repl()
+	// programs written before let and const declare variables by assigning to them
+	compat := flag.Bool("compat", false, "let assignment declare variables that don't exist yet")
+	tokens := flag.Bool("tokens", false, "save the tokens of the file to out/stmts.json")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		schemes.Repl(*compat)
 		return
 	}
 
-	if len(os.Args) != 2 {
-		panic("Usage: go run main.go <filename>")
+	if flag.NArg() != 1 {
+		panic("Usage: go run main.go [-compat] [-tokens] <filename>")
 	}
 
 	// get arguments
-	filename := os.Args[1]
-	schemes.Exec(filename)
+	filename := flag.Arg(0)
+	schemes.Exec(filename, schemes.Options{Compat: *compat, DumpTokens: *tokens})
 }
@@ -1,24 +1,103 @@
 package gg
 
 import (
+	"errors"
 	"fmt"
 	"runtime"
+	"slices"
+	"strings"
 )
 
 type SyntaxErr struct {
+	Pos     Pos
 	Message string
+	// the tokens that would have been valid where the error was found, if known
+	Expected []string
+	// io.ErrUnexpectedEOF when the source ended before the error could be resolved
+	Err error
 }
 
+// the expected tokens are added to the first line of the message,
+// unless it starts with "expected" and already says what was
 func (err *SyntaxErr) Error() string {
-	return err.Message
+	msg := err.Message
+	if len(err.Expected) > 0 && !strings.HasPrefix(msg, "expected") {
+		first, rest, multiline := strings.Cut(msg, "\n")
+		msg = first + " (expected " + orList(err.Expected) + ")"
+		if multiline {
+			msg += "\n" + rest
+		}
+	}
+	return withPos(err.Pos, msg)
+}
+
+// a, b or c
+func orList(items []string) string {
+	if len(items) == 1 {
+		return items[0]
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " or " + items[len(items)-1]
+}
+
+func (err *SyntaxErr) Unwrap() error {
+	return err.Err
+}
+
+// SyntaxErrs holds every syntax error found in a source, in the order they were found
+type SyntaxErrs []*SyntaxErr
+
+func (errs SyntaxErrs) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (errs SyntaxErrs) Unwrap() []error {
+	ret := make([]error, len(errs))
+	for i, err := range errs {
+		ret[i] = err
+	}
+	return ret
+}
+
+// JoinSyntax merges the syntax errors in a and b into one SyntaxErrs ordered by position.
+// if either is another kind of error, that error is returned instead.
+func JoinSyntax(a, b error) error {
+	var errs SyntaxErrs
+	for _, err := range []error{a, b} {
+		if err == nil {
+			continue
+		}
+		var list SyntaxErrs
+		var single *SyntaxErr
+		switch {
+		case errors.As(err, &list):
+			errs = append(errs, list...)
+		case errors.As(err, &single):
+			errs = append(errs, single)
+		default:
+			return err
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+
+	slices.SortStableFunc(errs, func(a, b *SyntaxErr) int {
+		return a.Pos.Compare(b.Pos)
+	})
+	return errs
 }
 
 type RuntimeErr struct {
+	Pos     Pos
 	Message string
 }
 
 func (err *RuntimeErr) Error() string {
-	return err.Message
+	return withPos(err.Pos, err.Message)
 }
 
 func Runtime(msg string, args ...interface{}) *RuntimeErr {
@@ -37,6 +116,11 @@ func Syntax(msg string, args ...interface{}) *SyntaxErr {
 	return &SyntaxErr{Message: fmt.Sprintf(msg, args...)}
 }
 
+// SyntaxAt is Syntax for errors whose position is known when they are created
+func SyntaxAt(pos Pos, msg string, args ...interface{}) *SyntaxErr {
+	return &SyntaxErr{Pos: pos, Message: fmt.Sprintf(msg, args...)}
+}
+
 func Crit(msg string, args ...interface{}) *CritErr {
 	pc, file, no, ok := runtime.Caller(1)
 	details := runtime.FuncForPC(pc)
@@ -45,3 +129,10 @@ func Crit(msg string, args ...interface{}) *CritErr {
 	}
 	return &CritErr{msg: fmt.Sprintf(msg, args...)}
 }
+
+func withPos(pos Pos, msg string) string {
+	if !pos.IsValid() {
+		return msg
+	}
+	return pos.String() + ": " + msg
+}
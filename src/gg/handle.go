@@ -10,8 +10,14 @@ func Handle(err error) {
 		return
 	}
 	var chillErr *RuntimeErr
+	var syntaxErrs SyntaxErrs
+	var syntaxErr *SyntaxErr
 	var critErr *CritErr
 	switch {
+	case errors.As(err, &syntaxErrs):
+		panic(fmt.Sprintf("Syntax errors:\n%s\n", syntaxErrs.Error()))
+	case errors.As(err, &syntaxErr):
+		panic(fmt.Sprintf("Syntax error: %s\n", syntaxErr.Error()))
 	case errors.As(err, &chillErr):
 		panic(fmt.Sprintf("Runtime error: %s\n", chillErr.Error()))
 	case errors.As(err, &critErr):
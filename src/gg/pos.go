@@ -0,0 +1,34 @@
+package gg
+
+import "fmt"
+
+// Pos is a location in gg source code. Line and Col are 1-based and count runes,
+// the zero Pos means the location is unknown.
+type Pos struct {
+	File string `json:",omitempty"`
+	Line int
+	Col  int
+}
+
+func (p Pos) IsValid() bool {
+	return p.Line > 0
+}
+
+// formats the position as file.gg:12:5, or 12:5 when there is no file name
+func (p Pos) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+	if p.File == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Col)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Col)
+}
+
+// orders positions by line, then column
+func (p Pos) Compare(o Pos) int {
+	if p.Line != o.Line {
+		return p.Line - o.Line
+	}
+	return p.Col - o.Col
+}
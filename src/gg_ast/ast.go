@@ -1,5 +1,16 @@
 package gg_ast
 
+import "gg-lang/src/gg"
+
 type Ast struct {
 	Body []Expression
 }
+
+// Span is the region of source an expression was parsed from, from its first
+// token up to the end of its last token.
+type Span struct {
+	Start gg.Pos
+	End   gg.Pos
+}
+
+func (s Span) Pos() gg.Pos { return s.Start }
@@ -1,38 +1,76 @@
 package gg_ast
 
 import (
+	"errors"
 	"gg-lang/src/gg"
 	"gg-lang/src/parser"
 	"gg-lang/src/token"
+	"io"
+	"strings"
 )
 
+// the builder is the parser the expression parsers work with,
+// along with the state that has to outlive a single expression
 type builder struct {
-	par *parser.Parser[token.Token]
+	*parser.Parser[token.Token]
+
+	// every syntax error found so far
+	errs gg.SyntaxErrs
+	// how many blocks the parser is in
+	blockDepth int
+	// set while parsing the statements of a block that's used as a value, like the body of
+	// an if expression, whose last expression is its value and doesn't need a ;
+	valueBlock bool
+	// the labels of the loops the parser is in, innermost last. unlabeled loops are ""
+	loops []string
+	// set while parsing the top level of a match guard, where => ends the guard instead of
+	// starting an arrow routine. parentheses and argument lists in the guard clear it.
+	inGuard bool
+	// the names declared in each block the parser is in, innermost last.
+	// true for constants, so assignments to them can be reported before the program runs
+	scopes []map[string]bool
+}
+
+func parseBlockStatement(p tokenParser) (BlockStatement, error) {
+	return parseBlock(p, false)
 }
 
-func parseBlockStatement(par *parser.Parser[token.Token]) (BlockStatement, error) {
-	if !advanceIfCurrIs(par, token.OpenBrace) {
-		return nil, gg.Syntax("expected opening brace for block statement\n%s", par.String())
+// parses a block whose last expression is its value when isValue is set
+func parseBlock(p tokenParser, isValue bool) (BlockStatement, error) {
+	if err := expect(p, token.OpenBrace, "expected opening brace for block statement"); err != nil {
+		return nil, err
 	}
+	valueBlock := p.valueBlock
+	p.valueBlock = isValue
+	p.blockDepth++
+	p.enterScope()
+	defer func() {
+		p.valueBlock = valueBlock
+		p.blockDepth--
+		p.exitScope()
+	}()
+
 	var expressions []Expression
-	for par.HasCurr {
-		if advanceIfCurrIs(par, token.CloseBrace) {
+	for skipIllegal(p) {
+		if advanceIfCurrIs(p, token.CloseBrace) {
 			return expressions, nil
 		}
 
-		stmt, err := parseExpression(par)
+		stmt, err := parseExpression(p)
 		if err != nil {
-			return nil, err
+			if !p.recover(err) {
+				return nil, err
+			}
+			continue
 		}
 
 		expressions = append(expressions, stmt)
 	}
 
-	return nil, gg.Syntax("no closing brace for block statement\n%s", par.String())
+	return nil, gg.Syntax("no closing brace for block statement\n%s", p.String())
 }
 
-func newAstBuilder(ins []token.Token) *builder {
-	par := parser.New(ins)
+func newAstBuilder(par *parser.Parser[token.Token]) *builder {
 	par.SetStringer(func(in token.Token) string {
 		if in.TokenType == token.Term {
 			return in.Symbol + "\n"
@@ -44,31 +82,218 @@ func newAstBuilder(ins []token.Token) *builder {
 	par.TruncAfter = 10
 
 	return &builder{
-		par: par,
+		Parser: par,
+		scopes: []map[string]bool{{}},
 	}
 }
 
 func BuildFromString(ins string) (*Ast, error) {
-	tokens, err := token.TokenizeRunes([]rune(ins))
-	if err != nil {
-		return nil, err
+	return BuildFromScanner(token.NewScanner(strings.NewReader(ins)))
+}
+
+// BuildFromScanner builds the Ast while pulling tokens from the scanner,
+// so the tokens are never all in memory at once
+func BuildFromScanner(s *token.Scanner) (*Ast, error) {
+	var readErr error
+	ast, err := newAstBuilder(scannerParser(s, &readErr)).build()
+	if readErr != nil {
+		return nil, readErr
 	}
 
-	return BuildFromTokens(tokens)
+	// lexical and syntax errors are reported together, in the order they appear in the source
+	return ast, gg.JoinSyntax(s.Errs(), err)
 }
 
+// BuildValueFromString parses src as a single value expression, like "a + b * c"
+func BuildValueFromString(src string) (ValueExpression, error) {
+	var readErr error
+	s := token.NewScanner(strings.NewReader(src))
+	b := newAstBuilder(scannerParser(s, &readErr))
+
+	expr, err := parseValueExpr(b)
+	if err == nil && b.HasCurr {
+		err = gg.Syntax("unexpected %s after the expression", b.Curr.Symbol)
+	}
+	if readErr != nil {
+		return nil, readErr
+	}
+	if err = gg.JoinSyntax(b.errs, withCurrPos(b, err)); err != nil {
+		return nil, gg.JoinSyntax(s.Errs(), err)
+	}
+	return expr, s.Errs()
+}
+
+// a parser that pulls tokens from s as it needs them.
+// read errors other than io.EOF end the input and are stored in readErr.
+func scannerParser(s *token.Scanner, readErr *error) *parser.Parser[token.Token] {
+	return parser.NewLazy(func() (token.Token, bool) {
+		tok, err := s.Next()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				*readErr = err
+			}
+			return token.Token{}, false
+		}
+		return tok, true
+	})
+}
+
+// BuildFromTokens builds the Ast from tokenized source. the errors of
+// illegal tokens are the tokenizer's to report, see skipIllegal.
 func BuildFromTokens(ins []token.Token) (*Ast, error) {
-	a := newAstBuilder(ins)
+	return newAstBuilder(parser.New(ins)).build()
+}
 
+// builds as much of the Ast as it can. statements with syntax errors are left out,
+// and the errors are returned together as gg.SyntaxErrs along with the partial Ast.
+func (a *builder) build() (*Ast, error) {
 	var expressions []Expression
-	for a.par.HasCurr {
-		expr, err := parseExpression(a.par)
+	for skipIllegal(a) {
+		expr, err := parseExpression(a)
 		if err != nil {
-			return nil, err
+			if !a.recover(err) {
+				return nil, err
+			}
+			continue
 		}
 
 		expressions = append(expressions, expr)
 	}
 
-	return &Ast{Body: expressions}, nil
+	ast := &Ast{Body: expressions}
+	if len(a.errs) > 0 {
+		return ast, a.errs
+	}
+	return ast, nil
+}
+
+// sets inGuard and returns a func that restores it
+func (a *builder) setInGuard(inGuard bool) func() {
+	saved := a.inGuard
+	a.inGuard = inGuard
+	return func() { a.inGuard = saved }
+}
+
+func (a *builder) enterScope() {
+	a.scopes = append(a.scopes, map[string]bool{})
+}
+
+func (a *builder) exitScope() {
+	a.scopes = a.scopes[:len(a.scopes)-1]
+}
+
+// declares names in the innermost scope
+func (a *builder) declare(isConst bool, names ...string) {
+	for _, name := range names {
+		a.scopes[len(a.scopes)-1][name] = isConst
+	}
+}
+
+// reports whether name is declared in the innermost scope
+func (a *builder) declaredHere(name string) bool {
+	_, ok := a.scopes[len(a.scopes)-1][name]
+	return ok
+}
+
+// reports an assignment to any of names that refers to a constant. names the parser
+// hasn't seen declared may still be declared by the time the assignment runs,
+// those are left to the program.
+func (a *builder) checkNotConst(pos gg.Pos, names ...string) {
+	for _, name := range names {
+		for i := len(a.scopes) - 1; i >= 0; i-- {
+			isConst, ok := a.scopes[i][name]
+			if !ok {
+				continue
+			}
+			if isConst {
+				a.report(gg.SyntaxAt(pos, "cannot assign to constant %s", name))
+			}
+			break
+		}
+	}
+}
+
+// records a syntax error in a statement that could still be parsed
+func (a *builder) report(err *gg.SyntaxErr) {
+	a.errs = append(a.errs, err)
+}
+
+// records a syntax error and skips to where parsing can resume.
+// returns false if err isn't a syntax error, those can't be recovered from.
+func (a *builder) recover(err error) bool {
+	err = withCurrPos(a, err)
+	var synErr *gg.SyntaxErr
+	if !errors.As(err, &synErr) {
+		return false
+	}
+	a.errs = append(a.errs, synErr)
+	a.synchronize()
+	return true
+}
+
+// skips the rest of a statement that failed to parse. a statement ends after a ;
+// or a block's } at the level it started on, or before the } that closes the block it's in.
+func (a *builder) synchronize() {
+	depth := 0
+	for a.HasCurr {
+		switch a.Curr.TokenType {
+		case token.OpenBrace:
+			depth++
+		case token.CloseBrace:
+			if depth == 0 {
+				// leave it to close the enclosing block, a stray } at the top level is skipped
+				if a.blockDepth == 0 {
+					a.Advance()
+				}
+				return
+			}
+			depth--
+			if depth == 0 && !continuesStatement(a.Next.TokenType) {
+				a.Advance()
+				return
+			}
+		case token.Term:
+			if depth == 0 {
+				a.Advance()
+				return
+			}
+		}
+		a.Advance()
+	}
+}
+
+// true for the keywords that continue a statement after the } of its first block
+func continuesStatement(tt token.Type) bool {
+	return tt == token.Else || tt == token.Catch || tt == token.Finally
+}
+
+// illegal tokens are kept in the token stream so a bad literal like 0b102 can stand in for the
+// operand it was meant to be, see IllegalExpression. anywhere else an illegal token is a stray
+// character the scanner already reported, and it's skipped. returns whether there are tokens left.
+func skipIllegal(p tokenParser) bool {
+	for p.HasCurr && p.Curr.TokenType == token.Illegal {
+		p.Advance()
+	}
+	return p.HasCurr
+}
+
+// syntax errors are raised at the token the parser is stuck on, so any syntax
+// error without a position gets the position of the current token
+func withCurrPos(p tokenParser, err error) error {
+	var synErr *gg.SyntaxErr
+	if !errors.As(err, &synErr) || synErr.Pos.IsValid() {
+		return err
+	}
+
+	if p.HasCurr {
+		synErr.Pos = p.Curr.Pos
+		return err
+	}
+
+	// the parser ran out of tokens, the error could be fixed by more input
+	synErr.Err = io.ErrUnexpectedEOF
+	if prev, ok := p.Prev(); ok {
+		synErr.Pos = prev.EndPos
+	}
+	return err
 }
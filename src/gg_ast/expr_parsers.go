@@ -3,8 +3,9 @@ package gg_ast
 import (
 	"gg-lang/src/gg"
 	"gg-lang/src/operators"
-	"gg-lang/src/parser"
 	"gg-lang/src/token"
+	"slices"
+	"strings"
 )
 
 /*
@@ -19,81 +20,88 @@ pointing to the first token in the expression.
 After a successful parse, the parser should be pointing to the token after the expression
 */
 
-type tokenParser = *parser.Parser[token.Token]
+type tokenParser = *builder
 
 // a statement is a function call, declaration, assignment expression, or a for loop expression
 // it is up to the builder to disallow these expressions if it's not parsing the top level
 func parseExpression(p tokenParser) (Expression, error) {
 	// first expression should be an identifier, a reserved keyword, or a value expression
 	// check reserved keywords first
-	if p.Curr.TokenType == token.Function {
-		return parseFuncDecl(p)
+	// an anonymous routine can only be a statement if it's called right away
+	if p.Curr.TokenType == token.Function && p.Next.TokenType != token.OpenParen {
+		decl, err := parseFuncDecl(p)
+		if err != nil {
+			return nil, err
+		}
+		p.declare(false, decl.Target.Tok.Symbol)
+		return decl, nil
+	}
+	if p.Curr.TokenType == token.Let || p.Curr.TokenType == token.Const {
+		decl, err := parseDeclaration(p)
+		if err != nil {
+			return nil, err
+		}
+		if err := expect(p, token.Term, "expected ; after declaration"); err != nil {
+			return nil, err
+		}
+		return decl, nil
 	}
 	if p.Curr.TokenType == token.Try {
 		return parseTryCatchExpr(p)
 	}
 	if p.Curr.TokenType == token.For {
-		return parseForLoopExpr(p)
+		return parseForLoopExpr(p, "")
+	}
+	if p.Curr.TokenType == token.Ident && p.Next.TokenType == token.Colon {
+		return parseLabeledExpr(p)
+	}
+	if p.Curr.TokenType == token.Break || p.Curr.TokenType == token.Continue {
+		return parseLoopControlExpr(p)
 	}
+	// an if or match that ends a block used as a value is the block's value
 	if p.Curr.TokenType == token.If {
-		return parseIfElseExpr(p)
+		return parseIfElseExpr(p, p.valueBlock)
 	}
 	if p.Curr.TokenType == token.Return {
 		return parseReturnExpr(p)
 	}
-	if p.Curr.TokenType == token.OpenBrace {
-		return parseObjectExpr(p)
+	if p.Curr.TokenType == token.Match {
+		return parseMatchExpr(p, p.valueBlock)
 	}
-	if p.Curr.TokenType == token.OpenParen {
-		return parseParenExpr(p)
+	if (p.Curr.TokenType == token.OpenBracket || p.Curr.TokenType == token.OpenBrace) && groupFollowedBy(p, token.Assign) {
+		return parseDestructuringAssignment(p)
 	}
-	if p.Curr.TokenType == token.OpenBracket {
-		return parseArrayDeclExpr(p)
+	if p.Curr.TokenType == token.OpenBrace {
+		return parseObjectExpr(p)
 	}
 
 	// now it could be a function call or an assignment expression, both of which
-	// have to start with an identifier. this means no unassigned value expressions
-	// other than function calls are allowed at the top level.
-	id, err := parseIdentifier(p)
+	// start with a value expression. no unassigned value expressions other than
+	// function calls are allowed as statements, except for the last expression of
+	// a block that's used as a value.
+	first, err := parseValueExpr(p)
 	if err != nil {
 		return nil, err
 	}
-
-	if p.Curr.TokenType == token.Dot {
-		expr, err := parseDotAccessExpr(id, p)
-		if err != nil {
-			return nil, err
-		}
-		if advanceIfCurrIs(p, token.Assign) {
-			return parseDotAccessAssignExpr(expr, p)
-		}
+	if p.valueBlock && p.Curr.TokenType == token.CloseBrace {
+		return first, nil
 	}
-
-	if p.Curr.TokenType == token.Assign {
-		p.Advance()
-		return parseAssignmentExpr(id, p)
+	stmt, err := finishSimpleStmt(first, p)
+	if err != nil {
+		return nil, err
 	}
 
-	if p.Curr.TokenType == token.OpenParen {
-		expr, err := parseFuncCallExpr(id, p)
-		if err != nil {
-			return nil, err
-		}
-		if !advanceIfCurrIs(p, token.Term) {
-			return nil, gg.Syntax("expected ; after top-level function call\n%s", p.String())
+	msg := "expected ; after top-level function call"
+	if assign, ok := stmt.(*AssignmentExpression); ok {
+		msg = "expected ; after assignment expression"
+		if assign.Value == nil {
+			msg = "expected ; after " + assign.Target.Name() + assign.Op.Symbol
 		}
-		return expr, nil
 	}
-
-	if p.Curr.TokenType == token.OpenBracket {
-		expr, err := parseArrayAccessExpr(id, p)
-		if err != nil {
-			return nil, err
-		}
-		return parseArrayIndexAssignExpr(expr, p)
+	if err := expect(p, token.Term, msg); err != nil {
+		return nil, err
 	}
-
-	return nil, gg.Syntax("invalid top-level expression: %s \nin %s", p.Curr.Symbol, p.String())
+	return stmt, nil
 
 	////// if no operator, it's a function call
 	//expr, err := parseFuncCallExpr(id, p)
@@ -114,55 +122,112 @@ If the expression starts with a keyword, p will be pointing to the next token.
 After a successful parse, the parser should be pointing to the token after the expression
 */
 func parseObjectExpr(p tokenParser) (ValueExpression, error) {
-	if !advanceIfCurrIs(p, token.OpenBrace) {
-		return nil, gg.Syntax("expected opening brace for object expression\n%s", p.String())
+	start := p.Curr
+	if err := expect(p, token.OpenBrace, "expected opening brace for object expression"); err != nil {
+		return nil, err
 	}
-	props := make(map[string]ValueExpression)
+	var props []ObjectProperty
 	for p.HasCurr && p.Curr.TokenType != token.CloseBrace {
-		prop, err := parseIdentifier(p)
+		prop, err := parseObjectProperty(p)
 		if err != nil {
 			return nil, err
 		}
-		if prop.Kind() != ExprVariable {
-			return nil, gg.Crit("expected identifier as object property name, got %s instead in\n%s", prop.Name(), p.String())
-		}
-		if !advanceIfCurrIs(p, token.Colon) {
-			return nil, gg.Syntax("expected ':' after object property name\n%s", p.String())
+		props = append(props, prop)
+		if !advanceIfCurrIs(p, token.Comma) {
+			break
 		}
-		expr, err := parseValueExpr(p)
+	}
+	if err := expectListEnd(p, token.CloseBrace, "expected '}' or ',' after object property"); err != nil {
+		return nil, err
+	}
+	return &ObjectExpression{Span: span(p, start.Pos), Properties: props}, nil
+}
 
+func parseObjectProperty(p tokenParser) (ObjectProperty, error) {
+	key := p.Curr
+	switch key.TokenType {
+	case token.Ellipsis:
+		spread, err := parseSpreadExpr(p)
+		return ObjectProperty{Value: spread}, err
+	case token.OpenBracket:
+		// {[key]: value}
+		p.Advance()
+		computed, err := parseValueExpr(p)
 		if err != nil {
-			return nil, err
+			return ObjectProperty{}, err
 		}
-		props[prop.Tok.Symbol] = expr
-		if !advanceIfCurrIs(p, token.Comma) {
-			break
+		if err := expect(p, token.CloseBracket, "expected ']' after computed property name"); err != nil {
+			return ObjectProperty{}, err
 		}
+		value, err := parsePropertyValue(p)
+		return ObjectProperty{Computed: computed, Value: value}, err
+	case token.StringLiteral:
+		p.Advance()
+		value, err := parsePropertyValue(p)
+		return ObjectProperty{Key: key.Symbol, Value: value}, err
+	case token.Ident:
+		p.Advance()
+		id := &Identifier{Span: Span{Start: key.Pos, End: key.EndPos}, Tok: key, idKind: IdExprVariable}
+		switch p.Curr.TokenType {
+		case token.Comma, token.CloseBrace:
+			// {x} is short for {x: x}
+			return ObjectProperty{Key: key.Symbol, Value: id}, nil
+		case token.OpenParen:
+			// {greet(n) { }} is short for {greet: routine greet(n) { }}
+			method, err := parseFuncRest(p, key, id)
+			return ObjectProperty{Key: key.Symbol, Value: method}, err
+		}
+		value, err := parsePropertyValue(p)
+		return ObjectProperty{Key: key.Symbol, Value: value}, err
 	}
-	if !advanceIfCurrIs(p, token.CloseBrace) {
-		return nil, gg.Syntax("expected closing brace for object expression\n%s", p.String())
+
+	return ObjectProperty{}, expecting(gg.Syntax("expected property name, string or [ for object property, got %s instead in\n%s", key.Symbol, p.String()), token.Ident, token.StringLiteral, token.OpenBracket, token.Ellipsis)
+}
+
+// the : value after a property name
+func parsePropertyValue(p tokenParser) (ValueExpression, error) {
+	if err := expect(p, token.Colon, "expected ':' after object property name"); err != nil {
+		return nil, err
+	}
+	return parseValueExpr(p)
+}
+
+// ...value, only allowed in array literals, object literals and call arguments
+func parseSpreadExpr(p tokenParser) (*SpreadExpression, error) {
+	start := p.Curr
+	if err := expect(p, token.Ellipsis, "expected '...' for spread"); err != nil {
+		return nil, err
+	}
+	value, err := parseValueExpr(p)
+	if err != nil {
+		return nil, err
 	}
-	return &ObjectExpression{Properties: props}, nil
+	return &SpreadExpression{Span: span(p, start.Pos), Value: value}, nil
 }
 
 func parseTryCatchExpr(p tokenParser) (Expression, error) {
-	if !advanceIfCurrIs(p, token.Try) {
-		return nil, gg.Syntax("expected 'try' keyword for try-catch expression\n%s", p.String())
+	start := p.Curr
+	if err := expect(p, token.Try, "expected 'try' keyword for try-catch expression"); err != nil {
+		return nil, err
 	}
 	tryBlock, err := parseBlockStatement(p)
 	if err != nil {
 		return nil, err
 	}
 
-	if !advanceIfCurrIs(p, token.Catch) {
-		return nil, gg.Syntax("expected 'catch' keyword for try-catch expression\n%s", p.String())
+	catchStart := p.Curr
+	if err := expect(p, token.Catch, "expected 'catch' keyword for try-catch expression"); err != nil {
+		return nil, err
 	}
 
+	// the error param is only visible in the catch block
+	p.enterScope()
+	defer p.exitScope()
 	parenParams, err := params(p, token.OpenParen, token.CloseParen)
 	if err != nil {
 		return nil, err
 	}
-	if len(parenParams) != 1 {
+	if len(parenParams) != 1 || parenParams[0].Pattern != nil || parenParams[0].Default != nil || parenParams[0].IsRest {
 		return nil, gg.Syntax("catch statement requires 1 parameter\n%s", p.String())
 	}
 	catchBlock, err := parseBlockStatement(p)
@@ -173,7 +238,8 @@ func parseTryCatchExpr(p tokenParser) (Expression, error) {
 	expr := &TryCatchExpression{
 		Try: &tryBlock,
 		Catch: &CatchExpression{
-			ErrorParam: parenParams[0].Symbol,
+			Span:       span(p, catchStart.Pos),
+			ErrorParam: parenParams[0].Name.Symbol,
 			Body:       &catchBlock,
 		},
 	}
@@ -186,74 +252,451 @@ func parseTryCatchExpr(p tokenParser) (Expression, error) {
 		expr.Finally = &finallyBlock
 	}
 
+	expr.Span = span(p, start.Pos)
 	return expr, nil
 }
 
 func parseParenExpr(p tokenParser) (ValueExpression, error) {
-	if !advanceIfCurrIs(p, token.OpenParen) {
-		return nil, gg.Syntax("expected opening parenthesis for parenthesized expression\n%s", p.String())
+	start := p.Curr
+	if err := expect(p, token.OpenParen, "expected opening parenthesis for parenthesized expression"); err != nil {
+		return nil, err
 	}
+	defer p.setInGuard(false)()
 	expr, err := parseValueExpr(p)
 	if err != nil {
 		return nil, err
 	}
-	if !advanceIfCurrIs(p, token.CloseParen) {
-		return nil, gg.Syntax("expected closing parenthesis for parenthesized expression\n%s", p.String())
+	if err := expect(p, token.CloseParen, "expected closing parenthesis for parenthesized expression"); err != nil {
+		return nil, err
 	}
-	return &ParenthesizedExpression{Expr: expr}, nil
+	return &ParenthesizedExpression{Span: span(p, start.Pos), Expr: expr}, nil
 }
 
-func parseDotAccessExpr(id *Identifier, p tokenParser) (*DotAccessExpression, error) {
-	if !advanceIfCurrIs(p, token.Dot) {
-		return nil, gg.Syntax("expected '.' after dot access expression\n%s", p.String())
+func parseDotAccessExpr(obj ValueExpression, p tokenParser) (*DotAccessExpression, error) {
+	if err := expect(p, token.Dot, "expected '.' after dot access expression"); err != nil {
+		return nil, err
 	}
 
-	chain := []string{id.Name()}
-	for p.HasCurr && p.Curr.TokenType == token.Ident {
-		chain = append(chain, p.Curr.Symbol)
-		p.Advance()
-		if !advanceIfCurrIs(p, token.Dot) {
-			break
-		}
+	prop := p.Curr
+	if err := expect(p, token.Ident, "expected identifier after '.'"); err != nil {
+		return nil, err
 	}
 
-	if len(chain) <= 1 {
-		return nil, gg.Syntax("expected identifier after '.'\n%s", p.String())
+	return &DotAccessExpression{Span: span(p, obj.Pos()), Object: obj, Property: prop.Symbol}, nil
+}
+
+// a simple statement is an assignment, an increment or decrement, or a function call.
+// these are the statements allowed in the clauses of a counted for loop, so the ; after
+// them is left to the caller.
+func parseSimpleStmt(p tokenParser) (Expression, error) {
+	target, err := parsePrimaryExpr(p)
+	if err != nil {
+		return nil, err
+	}
+	return finishSimpleStmt(target, p)
+}
+
+// parses the rest of a simple statement that starts with target
+func finishSimpleStmt(target ValueExpression, p tokenParser) (Expression, error) {
+	if p.Curr.TokenType.IsOperator() && operators.IsAssignment(p.Curr.Symbol) {
+		return parseAssignmentExpr(target, p)
+	}
+	if p.Curr.TokenType == token.Increment || p.Curr.TokenType == token.Decrement {
+		return parseIncrementExpr(target, p)
+	}
+	if call, ok := target.(*FunctionCallExpression); ok {
+		return call, nil
 	}
 
-	return &DotAccessExpression{AccessChain: chain}, nil
+	if p.Curr.TokenType == token.Term || p.Curr.TokenType == token.CloseBrace {
+		return nil, expecting(gg.SyntaxAt(target.Pos(), "invalid top-level expression, only assignments and calls can be statements\nin %s", p.String()), simpleStmtContinuations...)
+	}
+	return nil, expecting(gg.Syntax("invalid top-level expression: %s\nin %s", p.Curr.Symbol, p.String()), simpleStmtContinuations...)
 }
 
-func parseAssignmentExpr(target *Identifier, p tokenParser) (*AssignmentExpression, error) {
+func parseAssignmentExpr(target ValueExpression, p tokenParser) (*AssignmentExpression, error) {
+	if !IsAssignable(target) {
+		return nil, gg.SyntaxAt(target.Pos(), "cannot assign to %s, only variables, properties and array elements can be assigned to\n%s", target.Name(), p.String())
+	}
+	if target.Kind() == ExprVariable {
+		p.checkNotConst(target.Pos(), target.Name())
+	}
+	op := p.Curr
+	if !op.TokenType.IsOperator() || !operators.IsAssignment(op.Symbol) {
+		return nil, gg.Syntax("expected '=' after assignment target\n%s", p.String())
+	}
+	p.Advance()
+
 	expr, err := parseValueExpr(p)
 	if err != nil {
 		return nil, err
 	}
-	if !advanceIfCurrIs(p, token.Term) {
-		return nil, gg.Syntax("expected ; after assignment expression\n%s", p.String())
+
+	return &AssignmentExpression{Span: span(p, target.Pos()), Target: target, Op: op, Value: expr}, nil
+}
+
+func parseDestructuringAssignment(p tokenParser) (*DestructuringAssignment, error) {
+	start := p.Curr
+	pattern, err := parseDestructuringPattern(p)
+	if err != nil {
+		return nil, err
+	}
+	names := patternNames(pattern)
+	if name, ok := firstDuplicate(names); ok {
+		p.report(gg.SyntaxAt(start.Pos, "%s is assigned twice in the same destructuring assignment", name))
+	}
+	p.checkNotConst(start.Pos, names...)
+
+	if err := expect(p, token.Assign, "expected '=' after destructuring pattern"); err != nil {
+		return nil, err
+	}
+	value, err := parseValueExpr(p)
+	if err != nil {
+		return nil, err
+	}
+	res := &DestructuringAssignment{Span: span(p, start.Pos), Pattern: pattern, Value: value}
+	if err := expect(p, token.Term, "expected ; after destructuring assignment"); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// let x = 1 or const [a, b] = pair, without the ;
+func parseDeclaration(p tokenParser) (*DeclarationStatement, error) {
+	start := p.Curr
+	isConst := start.TokenType == token.Const
+	if !advanceIfCurrIs(p, token.Let) && !advanceIfCurrIs(p, token.Const) {
+		return nil, gg.Crit("expected 'let' or 'const' keyword in expression parser\n%s", p.String())
+	}
+
+	pattern, err := parseDestructuringPattern(p)
+	if err != nil {
+		return nil, err
+	}
+	checkDeclarable(p, pattern)
+	names := patternNames(pattern)
+	if name, ok := firstDuplicate(names); ok {
+		p.report(gg.SyntaxAt(start.Pos, "%s is declared twice in the same declaration", name))
+	}
+
+	res := &DeclarationStatement{Const: isConst, Pattern: pattern}
+	if advanceIfCurrIs(p, token.Assign) {
+		value, err := parseValueExpr(p)
+		if err != nil {
+			return nil, err
+		}
+		res.Value = value
+	} else if isConst {
+		p.report(gg.SyntaxAt(start.Pos, "const %s needs a value", strings.Join(names, ", ")))
+	} else if _, ok := pattern.(*BindingPattern); !ok {
+		p.report(gg.SyntaxAt(start.Pos, "a destructuring declaration needs a value"))
+	}
+
+	// the names are declared after the value, which can still see the names they shadow
+	for _, name := range names {
+		if p.declaredHere(name) {
+			p.report(gg.SyntaxAt(start.Pos, "%s is already declared in this scope", name))
+		}
+	}
+	p.declare(isConst, names...)
+
+	res.Span = span(p, start.Pos)
+	return res, nil
+}
+
+// returns the first name that appears more than once
+func firstDuplicate(names []string) (string, bool) {
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if seen[name] {
+			return name, true
+		}
+		seen[name] = true
+	}
+	return "", false
+}
+
+// a++; and a--; are assignments without a value
+func parseIncrementExpr(target ValueExpression, p tokenParser) (*AssignmentExpression, error) {
+	if !IsAssignable(target) {
+		return nil, gg.SyntaxAt(target.Pos(), "cannot increment or decrement %s, only variables, properties and array elements can be\n%s", target.Name(), p.String())
+	}
+	if target.Kind() == ExprVariable {
+		p.checkNotConst(target.Pos(), target.Name())
+	}
+	op := p.Curr
+	if !advanceIfCurrIs(p, token.Increment) && !advanceIfCurrIs(p, token.Decrement) {
+		return nil, gg.Syntax("expected '++' or '--' after target\n%s", p.String())
+	}
+
+	return &AssignmentExpression{Span: span(p, target.Pos()), Target: target, Op: op}, nil
+}
+
+// outer: for ... { }
+func parseLabeledExpr(p tokenParser) (Expression, error) {
+	label := p.Curr
+	p.Advance() // eat the label
+	p.Advance() // eat the colon
+
+	if p.Curr.TokenType != token.For {
+		return nil, gg.SyntaxAt(label.Pos, "label %s must be followed by a for loop\n%s", label.Symbol, p.String())
+	}
+	if slices.Contains(p.loops, label.Symbol) {
+		p.report(gg.SyntaxAt(label.Pos, "label %s is already used by an enclosing loop", label.Symbol))
+	}
+	return parseForLoopExpr(p, label.Symbol)
+}
+
+// break and continue, with an optional label of the loop they apply to
+func parseLoopControlExpr(p tokenParser) (Expression, error) {
+	start := p.Curr
+	p.Advance() // eat the break or continue keyword
+
+	label := ""
+	if p.Curr.TokenType == token.Ident {
+		label = p.Curr.Symbol
+		p.Advance()
+	}
+	if err := expect(p, token.Term, "expected ; after %s", start.Symbol); err != nil {
+		return nil, err
+	}
+
+	// these are well formed statements in the wrong place, so parsing goes on as usual
+	switch {
+	case len(p.loops) == 0:
+		p.report(gg.SyntaxAt(start.Pos, "%s outside of a loop", start.Symbol))
+	case label != "" && !slices.Contains(p.loops, label):
+		p.report(gg.SyntaxAt(start.Pos, "%s to unknown label %s, there is no enclosing loop with that label", start.Symbol, label))
 	}
 
-	return &AssignmentExpression{Target: target, Value: expr}, nil
+	if start.TokenType == token.Break {
+		return &BreakStatement{Span: span(p, start.Pos), Label: label}, nil
+	}
+	return &ContinueStatement{Span: span(p, start.Pos), Label: label}, nil
 }
 
-func parseForLoopExpr(p tokenParser) (*ForLoopExpression, error) {
+// the arm blocks are values when asValue is set
+func parseMatchExpr(p tokenParser, asValue bool) (*MatchExpression, error) {
+	start := p.Curr
+	if !advanceIfCurrIs(p, token.Match) {
+		return nil, gg.Crit("expected 'match' keyword in expression parser\n%s", p.String())
+	}
+
+	subject, err := parseValueExpr(p)
+	if err != nil {
+		return nil, err
+	}
+	if err := expect(p, token.OpenBrace, "expected '{' after the value to match"); err != nil {
+		return nil, err
+	}
+
+	res := &MatchExpression{Subject: subject}
+	for p.HasCurr && p.Curr.TokenType != token.CloseBrace {
+		arm, err := parseMatchArm(p, asValue)
+		if err != nil {
+			return nil, err
+		}
+		res.Arms = append(res.Arms, arm)
+
+		// arms are separated by commas, which are optional after a block
+		if !advanceIfCurrIs(p, token.Comma) {
+			if _, isBlock := arm.Body.(BlockStatement); !isBlock {
+				break
+			}
+		}
+	}
+	if err := expectListEnd(p, token.CloseBrace, "expected '}' or ',' after match arm"); err != nil {
+		return nil, err
+	}
+	if len(res.Arms) == 0 {
+		return nil, gg.SyntaxAt(start.Pos, "match needs at least one arm")
+	}
+
+	res.Span = span(p, start.Pos)
+	return res, nil
+}
+
+func parseMatchArm(p tokenParser, asValue bool) (*MatchArm, error) {
+	start := p.Curr
+	pattern, err := parsePattern(p)
+	if err != nil {
+		return nil, err
+	}
+	names := patternNames(pattern)
+	if name, ok := firstDuplicate(names); ok {
+		p.report(gg.SyntaxAt(start.Pos, "%s is bound twice in the same match pattern", name))
+	}
+	arm := &MatchArm{Pattern: pattern}
+	p.enterScope()
+	defer p.exitScope()
+	p.declare(false, names...)
+
+	if advanceIfCurrIs(p, token.If) {
+		restore := p.setInGuard(true)
+		guard, err := parseValueExpr(p)
+		restore()
+		if err != nil {
+			return nil, err
+		}
+		arm.Guard = guard
+	}
+	if err := expect(p, token.Arrow, "expected '=>' after match pattern"); err != nil {
+		return nil, err
+	}
+
+	// an arm with braces is always a block, an object has to be wrapped in parentheses
+	if p.Curr.TokenType == token.OpenBrace {
+		arm.Body, err = parseBlock(p, asValue)
+	} else {
+		arm.Body, err = parseValueExpr(p)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	arm.Span = span(p, start.Pos)
+	return arm, nil
+}
+
+func parseForLoopExpr(p tokenParser, label string) (Expression, error) {
+	start := p.Curr
 	if !advanceIfCurrIs(p, token.For) { // eat the for keyword
 		return nil, gg.Crit("expected 'for' keyword in expression parser\n%s", p.String())
 	}
 
-	condition, err := parseValueExpr(p)
+	if p.Curr.TokenType == token.Ident && (p.Next.TokenType == token.In || p.Next.TokenType == token.Comma) {
+		return parseForInExpr(p, start, label)
+	}
+	if (p.Curr.TokenType == token.OpenBracket || p.Curr.TokenType == token.OpenBrace) && groupFollowedBy(p, token.In) {
+		return parseForInExpr(p, start, label)
+	}
+
+	// variables declared by the init statement only live as long as the loop
+	p.enterScope()
+	defer p.exitScope()
+
+	res := &ForLoopExpression{Label: label}
+	// a counted loop starts with its init statement and a ;, which may be empty
+	if p.Curr.TokenType == token.Let {
+		init, err := parseDeclaration(p)
+		if err != nil {
+			return nil, err
+		}
+		if err := expect(p, token.Term, "expected ; after the init statement of a counted for loop"); err != nil {
+			return nil, err
+		}
+		res.Init = init
+	} else if !advanceIfCurrIs(p, token.Term) {
+		first, err := parseValueExpr(p)
+		if err != nil {
+			return nil, err
+		}
+
+		if p.Curr.TokenType == token.OpenBrace {
+			res.Condition = first
+		} else {
+			init, err := finishSimpleStmt(first, p)
+			if err != nil {
+				return nil, err
+			}
+			if err := expect(p, token.Term, "expected ; after the init statement of a counted for loop"); err != nil {
+				return nil, err
+			}
+			res.Init = init
+		}
+	}
+
+	if res.Condition == nil {
+		if p.Curr.TokenType != token.Term {
+			condition, err := parseValueExpr(p)
+			if err != nil {
+				return nil, err
+			}
+			res.Condition = condition
+		}
+		if err := expect(p, token.Term, "expected ; after the condition of a counted for loop"); err != nil {
+			return nil, err
+		}
+		if p.Curr.TokenType != token.OpenBrace {
+			post, err := parseSimpleStmt(p)
+			if err != nil {
+				return nil, err
+			}
+			res.Post = post
+		}
+	}
+
+	body, err := parseLoopBody(p, label)
+	if err != nil {
+		return nil, err
+	}
+	res.Body = body
+	res.Span = span(p, start.Pos)
+	return res, nil
+}
+
+// for x in xs {, the parser must be after the for keyword
+func parseForInExpr(p tokenParser, start token.Token, label string) (*ForInExpression, error) {
+	res := &ForInExpression{Label: label}
+	if p.Curr.TokenType == token.Ident && p.Next.TokenType == token.Comma {
+		res.Key = p.Curr.Symbol
+		p.Advance() // eat the key
+		p.Advance() // eat the comma
+	}
+
+	var names []string
+	if p.Curr.TokenType == token.Ident {
+		res.Value = p.Curr.Symbol
+		names = []string{res.Value}
+		p.Advance()
+	} else {
+		pattern, err := parseDestructuringPattern(p)
+		if err != nil {
+			return nil, err
+		}
+		checkDeclarable(p, pattern)
+		res.ValuePattern = pattern
+		names = patternNames(pattern)
+	}
+	if res.Key != "" {
+		names = append(names, res.Key)
+	}
+	if name, ok := firstDuplicate(names); ok {
+		p.report(gg.SyntaxAt(start.Pos, "for-in loop declares %s twice", name))
+	}
+
+	if err := expect(p, token.In, "expected 'in' after the variables of a for-in loop"); err != nil {
+		return nil, err
+	}
+
+	iterable, err := parseValueExpr(p)
 	if err != nil {
 		return nil, err
 	}
+	res.Iterable = iterable
 
-	body, err := parseBlockStatement(p)
+	p.enterScope()
+	p.declare(false, names...)
+	body, err := parseLoopBody(p, label)
+	p.exitScope()
 	if err != nil {
 		return nil, err
 	}
-	return &ForLoopExpression{Condition: condition, Body: body}, nil
+	res.Body = body
+	res.Span = span(p, start.Pos)
+	return res, nil
 }
 
-func parseIfElseExpr(p tokenParser) (*IfElseStatement, error) {
+// parses the body of a loop, inside of which break and continue are allowed
+func parseLoopBody(p tokenParser, label string) (BlockStatement, error) {
+	p.loops = append(p.loops, label)
+	defer func() { p.loops = p.loops[:len(p.loops)-1] }()
+	return parseBlockStatement(p)
+}
+
+// the blocks are values when asValue is set
+func parseIfElseExpr(p tokenParser, asValue bool) (*IfElseStatement, error) {
+	start := p.Curr
 	if !advanceIfCurrIs(p, token.If) { // eat the if keyword
 		return nil, gg.Crit("expected 'if' keyword in expression parser\n%s", p.String())
 	}
@@ -264,7 +707,7 @@ func parseIfElseExpr(p tokenParser) (*IfElseStatement, error) {
 	}
 	res.Condition = condition
 
-	body, err := parseBlockStatement(p)
+	body, err := parseBlock(p, asValue)
 	if err != nil {
 		return nil, err
 	}
@@ -272,13 +715,13 @@ func parseIfElseExpr(p tokenParser) (*IfElseStatement, error) {
 
 	if advanceIfCurrIs(p, token.Else) {
 		if p.Curr.TokenType == token.If {
-			alt, err := parseIfElseExpr(p)
+			alt, err := parseIfElseExpr(p, asValue)
 			if err != nil {
 				return nil, err
 			}
 			res.ElseExpression = alt
 		} else {
-			b, err := parseBlockStatement(p)
+			b, err := parseBlock(p, asValue)
 			if err != nil {
 				return nil, err
 			}
@@ -286,10 +729,12 @@ func parseIfElseExpr(p tokenParser) (*IfElseStatement, error) {
 		}
 	}
 
+	res.Span = span(p, start.Pos)
 	return res, nil
 }
 
 func parseReturnExpr(p tokenParser) (*ReturnStatement, error) {
+	start := p.Curr
 	if !advanceIfCurrIs(p, token.Return) { // eat the return keyword
 		return nil, gg.Crit("expected 'return' keyword in expression parser\n%s", p.String())
 	}
@@ -299,89 +744,191 @@ func parseReturnExpr(p tokenParser) (*ReturnStatement, error) {
 		return nil, err
 	}
 
-	if !advanceIfCurrIs(p, token.Term) {
-		return nil, gg.Syntax("expected ; after return expression\n%s", p.String())
+	if err := expect(p, token.Term, "expected ; after return expression"); err != nil {
+		return nil, err
 	}
 
-	return &ReturnStatement{Value: expr}, nil
+	return &ReturnStatement{Span: span(p, start.Pos), Value: expr}, nil
 }
 
-func params(p tokenParser, open token.Type, close token.Type) ([]token.Token, error) {
-	if !advanceIfCurrIs(p, open) {
-		return nil, gg.Runtime("expected '(' after function name\n%s", p.String())
+func params(p tokenParser, open token.Type, close token.Type) ([]Param, error) {
+	if err := expect(p, open, "expected '%s' to open parameter list", open); err != nil {
+		return nil, err
 	}
 
-	var params []token.Token
+	var params []Param
+	seen := make(map[string]bool)
 	for {
 		if !p.HasCurr {
-			return nil, gg.Runtime("Unexpected end of param list\n%s", p.String())
+			return nil, gg.Syntax("unexpected end of param list\n%s", p.String())
 		}
-		param := p.Curr
-		if param.TokenType == close {
+		if p.Curr.TokenType == close {
 			p.Advance() // eat the closing parenthesis ')'
 			break
 		}
-		if param.TokenType == token.Comma {
+		if p.Curr.TokenType == token.Comma {
 			p.Advance() // eat the comma
 			continue
 		}
-		if param.TokenType != token.Ident {
-			return nil, gg.Runtime("Unexpected token\n%s", p.String())
+		if len(params) > 0 && params[len(params)-1].IsRest {
+			return nil, gg.Syntax("rest parameter ...%s must be the last parameter\n%s", params[len(params)-1].Name.Symbol, p.String())
 		}
 
+		start := p.Curr
+		param := Param{IsRest: advanceIfCurrIs(p, token.Ellipsis)}
+		names := []string{p.Curr.Symbol}
+		switch {
+		case p.Curr.TokenType == token.Ident:
+			param.Name = p.Curr
+			p.Advance()
+		case !param.IsRest && (p.Curr.TokenType == token.OpenBracket || p.Curr.TokenType == token.OpenBrace):
+			pattern, err := parseDestructuringPattern(p)
+			if err != nil {
+				return nil, err
+			}
+			checkDeclarable(p, pattern)
+			param.Pattern = pattern
+			names = patternNames(pattern)
+		default:
+			return nil, expecting(gg.Syntax("unexpected token %s in param list\n%s", p.Curr.Symbol, p.String()), token.Ident, token.Ellipsis, token.OpenBracket, token.OpenBrace, token.CloseParen)
+		}
+
+		for _, name := range names {
+			if seen[name] {
+				p.report(gg.SyntaxAt(start.Pos, "duplicate parameter %s", name))
+			}
+			seen[name] = true
+		}
+		p.declare(false, names...)
+
+		if p.Curr.TokenType == token.Assign {
+			if param.IsRest {
+				return nil, gg.Syntax("rest parameter ...%s can't have a default value\n%s", param.Name.Symbol, p.String())
+			}
+			p.Advance() // eat the =
+			def, err := parseValueExpr(p)
+			if err != nil {
+				return nil, err
+			}
+			param.Default = def
+		}
 		params = append(params, param)
-		p.Advance()
 	}
 	return params, nil
 }
 
 func parseFuncDecl(p tokenParser) (*FunctionDeclExpression, error) {
+	start := p.Curr
 	p.Advance() // eat the function keyword
 
-	id, err := parseIdentifier(p)
-	if err != nil {
-		return nil, err
+	// routine (a, b) { } is anonymous
+	var id *Identifier
+	if p.Curr.TokenType != token.OpenParen {
+		var err error
+		if id, err = parseIdentifier(p); err != nil {
+			return nil, err
+		}
 	}
+	return parseFuncRest(p, start, id)
+}
 
+// parses the params and the body of a routine that started at start
+func parseFuncRest(p tokenParser, start token.Token, id *Identifier) (*FunctionDeclExpression, error) {
+	// the params are visible in the body
+	p.enterScope()
+	defer p.exitScope()
 	params, err := params(p, token.OpenParen, token.CloseParen)
 	if err != nil {
 		return nil, err
 	}
 
-	block, err := parseBlockStatement(p)
+	block, err := parseFuncBody(p)
 	if err != nil {
 		return nil, err
 	}
 
 	return &FunctionDeclExpression{
+		Span:   span(p, start.Pos),
 		Target: id,
 		Params: params,
 		Body:   block,
 	}, nil
 }
 
-func parseDotAccessAssignExpr(target *DotAccessExpression, p tokenParser) (*DotAccessAssignmentExpression, error) {
-	val, err := parseValueExpr(p)
+// (a, b) => a + b or (a, b) => { }
+func parseArrowFunc(p tokenParser) (*FunctionDeclExpression, error) {
+	start := p.Curr
+	p.enterScope()
+	defer p.exitScope()
+	params, err := params(p, token.OpenParen, token.CloseParen)
 	if err != nil {
 		return nil, err
 	}
+	if err := expect(p, token.Arrow, "expected '=>' after arrow routine parameters"); err != nil {
+		return nil, err
+	}
 
-	if !advanceIfCurrIs(p, token.Term) {
-		return nil, gg.Syntax("expected ; after dot-access assignment expression\n%s", p.String())
+	// like in match arms, braces are always a block
+	var body BlockStatement
+	if p.Curr.TokenType == token.OpenBrace {
+		body, err = parseFuncBody(p)
+	} else {
+		var value ValueExpression
+		value, err = parseValueExpr(p)
+		if value != nil {
+			body = BlockStatement{&ReturnStatement{Span: span(p, value.Pos()), Value: value}}
+		}
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	return &DotAccessAssignmentExpression{
-		Target: target,
-		Value:  val,
-	}, nil
+	return &FunctionDeclExpression{Span: span(p, start.Pos), Params: params, Body: body}, nil
+}
+
+func parseFuncBody(p tokenParser) (BlockStatement, error) {
+	// break and continue can't reach the loops around a routine
+	loops := p.loops
+	p.loops = nil
+	defer func() { p.loops = loops }()
+	return parseBlockStatement(p)
+}
+
+// an arrow routine starts like a parenthesized expression, it's told apart
+// by the => after the parenthesis that closes its parameters
+func isArrowFunc(p tokenParser) bool {
+	return !p.inGuard && p.Curr.TokenType == token.OpenParen && groupFollowedBy(p, token.Arrow)
+}
+
+// reports whether the parenthesis, bracket or brace at Curr is closed and followed by a token of type tt,
+// without advancing the parser
+func groupFollowedBy(p tokenParser, tt token.Type) bool {
+	depth := 0
+	for i := 0; ; i++ {
+		tok, ok := p.Peek(i)
+		if !ok {
+			return false
+		}
+		switch tok.TokenType {
+		case token.OpenParen, token.OpenBracket, token.OpenBrace:
+			depth++
+		case token.CloseParen, token.CloseBracket, token.CloseBrace:
+			depth--
+			if depth == 0 {
+				next, ok := p.Peek(i + 1)
+				return ok && next.TokenType == tt
+			}
+		}
+	}
 }
 
 func parseArrayDeclExpr(p tokenParser) (*ArrayDeclExpression, error) {
+	start := p.Curr
 	members, err := arguments(p, token.OpenBracket, token.CloseBracket)
 	if err != nil {
 		return nil, err
 	}
-	return &ArrayDeclExpression{Elements: members}, nil
+	return &ArrayDeclExpression{Span: span(p, start.Pos), Elements: members}, nil
 }
 
 func parseIdentifier(p tokenParser) (*Identifier, error) {
@@ -390,6 +937,8 @@ func parseIdentifier(p tokenParser) (*Identifier, error) {
 	switch t.TokenType {
 	case token.IntLiteral:
 		ik = IdExprNumber
+	case token.FloatLiteral:
+		ik = IdExprFloat
 	case token.Ident:
 		ik = IdExprVariable
 	case token.StringLiteral:
@@ -398,132 +947,257 @@ func parseIdentifier(p tokenParser) (*Identifier, error) {
 		ik = IdExprBool
 	case token.FalseLiteral:
 		ik = IdExprBool
+	case token.NilLiteral:
+		ik = IdExprNil
 	default:
-		return nil, gg.Runtime("invalid identifier %s, in\n$s", t.Symbol, p.String())
+		return nil, expecting(gg.Syntax("invalid identifier %s\n%s", t.Symbol, p.String()), valueStarts...)
 	}
 	p.Advance()
-	return &Identifier{Tok: t, idKind: ik}, nil
+	return &Identifier{Span: Span{Start: t.Pos, End: t.EndPos}, Tok: t, idKind: ik}, nil
 }
 
-// returns a primary expression or a binary expression
+// returns a primary expression, a binary expression or a conditional expression
 func parseValueExpr(p tokenParser) (ValueExpression, error) {
-	if !p.HasCurr {
-		return nil, gg.Runtime("unexpected end of expression\n%s", p.String())
+	cond, err := parseBinaryExpr(p, operators.PrecNullCoalesce)
+	if err != nil {
+		return nil, err
+	}
+	if !advanceIfCurrIs(p, token.Question) {
+		return cond, nil
 	}
 
-	// build initial binary tree
-	lhsNonBinary, err := parsePrimaryExpr(p)
+	// the branches are full value expressions, so a ? b : c ? d : e is a ? b : (c ? d : e)
+	then, err := parseValueExpr(p)
 	if err != nil {
 		return nil, err
 	}
-
-	if !p.Curr.TokenType.IsOperator() {
-		return lhsNonBinary, nil
+	if err := expect(p, token.Colon, "expected ':' after the first branch of '?'"); err != nil {
+		return nil, err
 	}
-
-	op := p.Curr
-	p.Advance() // eat the operator token
-
-	rhs, err := parsePrimaryExpr(p)
+	els, err := parseValueExpr(p)
 	if err != nil {
 		return nil, err
 	}
+	return &ConditionalExpression{Span: span(p, cond.Pos()), Condition: cond, Then: then, Else: els}, nil
+}
 
-	lhs := &BinaryExpression{
-		Lhs: lhsNonBinary,
-		Op:  op,
-		Rhs: rhs,
+// parses operands joined by binary operators that bind at least as tightly as minPrec,
+// by precedence climbing. operators that bind looser are left for the caller.
+func parseBinaryExpr(p tokenParser, minPrec int) (ValueExpression, error) {
+	lhs, err := parseUnaryExpr(p)
+	if err != nil {
+		return nil, err
 	}
 
-	// add on to the initial tree
-	for {
-		op = p.Curr
-		if !op.TokenType.IsOperator() {
+	for p.HasCurr && p.Curr.TokenType.IsOperator() && operators.IsBinary(p.Curr.Symbol) {
+		op := p.Curr
+		binding, _ := operators.BindingOf(op.Symbol)
+		if binding.Prec < minPrec {
 			break
 		}
 		p.Advance() // eat the operator token
 
-		rhs, err := parsePrimaryExpr(p)
+		// the right operand of a left-associative operator may only hold operators that bind
+		// tighter, so a - b - c is (a - b) - c. a right-associative one takes equal ones too.
+		rhsPrec := binding.Prec + 1
+		if binding.RightAssoc {
+			rhsPrec = binding.Prec
+		}
+		rhs, err := parseBinaryExpr(p, rhsPrec)
 		if err != nil {
 			return nil, err
 		}
 
-		if operators.LeftFirst(lhs.Op.Symbol, op.Symbol) {
-			// left needs to be evaluated first and therefor deeper into the tree
-			lhs = &BinaryExpression{
-				Lhs: lhs,
-				Op:  op,
-				Rhs: rhs,
-			}
-		} else {
-			lhs.Rhs = &BinaryExpression{
-				Lhs: lhs.Rhs,
-				Op:  op,
-				Rhs: rhs,
-			}
+		lhs = &BinaryExpression{
+			Span: span(p, lhs.Pos()),
+			Lhs:  lhs,
+			Op:   op,
+			Rhs:  rhs,
 		}
 	}
 	return lhs, nil
 }
 
-// A primary expression is either an identifier, a literal, a function call, a unary binary expression, or a function declaration
-func parsePrimaryExpr(p tokenParser) (ValueExpression, error) {
+func parseUnaryExpr(p tokenParser) (ValueExpression, error) {
 	if !p.HasCurr {
-		return nil, gg.Runtime("unexpected end of expression\n%s", p.String())
+		return nil, gg.Syntax("unexpected end of expression\n%s", p.String())
 	}
-
-	// unary operators
-	if p.Curr.TokenType.IsOperator() {
-		op := p.Curr
-		p.Advance()
-		toNegate, err := parsePrimaryExpr(p)
-		if err != nil {
-			return nil, err
-		}
-
-		return &UnaryExpression{
-			Op:  op,
-			Rhs: toNegate,
-		}, nil
+	if !p.Curr.TokenType.IsOperator() {
+		return parsePrimaryExpr(p)
 	}
 
-	if p.Curr.TokenType == token.Function {
-		return parseFuncDecl(p)
+	op := p.Curr
+	if !operators.IsPrefix(op.Symbol) {
+		return nil, expecting(gg.Syntax("unexpected operator %s at the start of an expression\n%s", op.Symbol, p.String()), valueStarts...)
 	}
+	p.Advance()
 
-	if p.Curr.TokenType == token.OpenBrace {
-		return parseObjectExpr(p)
+	operand, err := parseBinaryExpr(p, operators.PrecPrefix)
+	if err != nil {
+		return nil, err
 	}
 
-	if p.Curr.TokenType == token.OpenParen {
-		return parseParenExpr(p)
-	}
+	return &UnaryExpression{
+		Span: span(p, op.Pos),
+		Op:   op,
+		Rhs:  operand,
+	}, nil
+}
 
-	if p.Curr.TokenType == token.OpenBracket {
-		return parseArrayDeclExpr(p)
+// A primary expression is an identifier, a literal or a function declaration,
+// followed by any chain of member accesses, indexes and calls
+func parsePrimaryExpr(p tokenParser) (ValueExpression, error) {
+	if !p.HasCurr {
+		return nil, gg.Syntax("unexpected end of expression\n%s", p.String())
+	}
+
+	var expr ValueExpression
+	var err error
+	switch p.Curr.TokenType {
+	case token.Function:
+		expr, err = parseFuncDecl(p)
+	case token.OpenBrace:
+		expr, err = parseObjectExpr(p)
+	case token.OpenParen:
+		if isArrowFunc(p) {
+			expr, err = parseArrowFunc(p)
+		} else {
+			expr, err = parseParenExpr(p)
+		}
+	case token.OpenBracket:
+		expr, err = parseArrayDeclExpr(p)
+	case token.TemplateHead:
+		expr, err = parseTemplateExpr(p)
+	case token.Match:
+		expr, err = parseMatchExpr(p, true)
+	case token.If:
+		expr, err = parseIfElseExpr(p, true)
+	case token.Illegal:
+		// a run of stray characters the scanner already reported, like the @ # in a = @ # 2,
+		// is one bad operand
+		tok := p.Curr
+		end := tok.EndPos
+		for p.HasCurr && p.Curr.TokenType == token.Illegal {
+			end = p.Curr.EndPos
+			p.Advance()
+		}
+		// it comes before the real operand, like the @ in a + @b
+		if p.HasCurr && startsOperand(p.Curr.TokenType) {
+			return parsePrimaryExpr(p)
+		}
+		expr = &IllegalExpression{Span: Span{Start: tok.Pos, End: end}, Tok: tok}
+	default:
+		expr, err = parseIdentifier(p)
 	}
-
-	id, err := parseIdentifier(p)
 	if err != nil {
 		return nil, err
 	}
 
-	if p.Curr.TokenType == token.Dot {
-		return parseDotAccessExpr(id, p)
+	return parsePostfixExpr(expr, p)
+}
+
+// wraps expr in every .name, [index] and (args) that follows it, so a.b[0](c)
+// is the call of the index of the access to b of a
+func parsePostfixExpr(expr ValueExpression, p tokenParser) (ValueExpression, error) {
+	for p.HasCurr {
+		var err error
+		switch p.Curr.TokenType {
+		case token.Dot:
+			expr, err = parseDotAccessExpr(expr, p)
+		case token.OpenBracket:
+			expr, err = parseArrayAccessExpr(expr, p)
+		case token.OpenParen:
+			expr, err = parseFuncCallExpr(expr, p)
+		case token.OptionalChain:
+			expr, err = parseOptionalChainExpr(expr, p)
+		case token.Illegal:
+			// stray characters after an operand, probably a bad operator like the @ in a @ b,
+			// so the operand after them is skipped too instead of being reported as well
+			skipIllegal(p)
+			if p.HasCurr && startsOperand(p.Curr.TokenType) {
+				_, err = parsePrimaryExpr(p)
+			}
+		default:
+			return expr, nil
+		}
+		if err != nil {
+			return nil, err
+		}
 	}
+	return expr, nil
+}
 
-	if p.Curr.TokenType == token.OpenParen {
-		return parseFuncCallExpr(id, p)
+// a?.b, a?.[i] or f?.(). each ?. only guards the access right after it,
+// so a?.b.c still fails when a is nil, and a?.b?.c is nil.
+func parseOptionalChainExpr(expr ValueExpression, p tokenParser) (ValueExpression, error) {
+	if !advanceIfCurrIs(p, token.OptionalChain) {
+		return nil, gg.Crit("expected '?.' in optional chain parser\n%s", p.String())
 	}
 
-	if p.Curr.TokenType == token.OpenBracket {
-		return parseArrayAccessExpr(id, p)
+	switch p.Curr.TokenType {
+	case token.Ident:
+		prop := p.Curr
+		p.Advance()
+		return &DotAccessExpression{Span: span(p, expr.Pos()), Object: expr, Property: prop.Symbol, Optional: true}, nil
+	case token.OpenBracket:
+		access, err := parseArrayAccessExpr(expr, p)
+		if err != nil {
+			return nil, err
+		}
+		access.Optional = true
+		return access, nil
+	case token.OpenParen:
+		call, err := parseFuncCallExpr(expr, p)
+		if err != nil {
+			return nil, err
+		}
+		call.Optional = true
+		return call, nil
+	}
+	return nil, expecting(gg.Syntax("expected a property name, '[' or '(' after '?.'\n%s", p.String()), token.Ident, token.OpenBracket, token.OpenParen)
+}
+
+// reports whether a token of type tt can be the first token of an operand
+func startsOperand(tt token.Type) bool {
+	return slices.Contains(operandStarts, tt)
+}
+
+// the tokenizer splits "a ${b} c" into a TemplateHead, the tokens of b, and a TemplateTail,
+// with a TemplateMiddle between every pair of expressions
+func parseTemplateExpr(p tokenParser) (*TemplateExpression, error) {
+	start := p.Curr
+	if !advanceIfCurrIs(p, token.TemplateHead) {
+		return nil, gg.Crit("expected template head in template parser\n%s", p.String())
+	}
+
+	res := &TemplateExpression{Strings: []string{start.Symbol}}
+	for {
+		if p.Curr.TokenType == token.TemplateMiddle || p.Curr.TokenType == token.TemplateTail {
+			return nil, gg.Syntax("empty string interpolation, expected an expression inside ${}\n%s", p.String())
+		}
+		expr, err := parseValueExpr(p)
+		if err != nil {
+			return nil, err
+		}
+		res.Exprs = append(res.Exprs, expr)
+
+		part := p.Curr
+		if !p.HasCurr || (part.TokenType != token.TemplateMiddle && part.TokenType != token.TemplateTail) {
+			return nil, gg.Syntax("expected '}' after string interpolation expression\n%s", p.String())
+		}
+		p.Advance()
+		res.Strings = append(res.Strings, part.Symbol)
+
+		if part.TokenType == token.TemplateTail {
+			break
+		}
 	}
 
-	return id, nil
+	res.Span = span(p, start.Pos)
+	return res, nil
 }
 
-func parseArrayAccessExpr(id *Identifier, p tokenParser) (*ArrayIndexExpression, error) {
+func parseArrayAccessExpr(arr ValueExpression, p tokenParser) (*ArrayIndexExpression, error) {
 	args, err := arguments(p, token.OpenBracket, token.CloseBracket)
 	if err != nil {
 		return nil, err
@@ -532,34 +1206,17 @@ func parseArrayAccessExpr(id *Identifier, p tokenParser) (*ArrayIndexExpression,
 		return nil, gg.Syntax("expected 1 argument in array access expression, got %d\n%s", len(args), p.String())
 	}
 	return &ArrayIndexExpression{
-		Array: id,
+		Span:  span(p, arr.Pos()),
+		Array: arr,
 		Index: args[0],
 	}, nil
 }
 
-func parseArrayIndexAssignExpr(arrayAccessExpr *ArrayIndexExpression, p tokenParser) (*ArrayIndexAssignmentExpression, error) {
-	if !advanceIfCurrIs(p, token.Assign) {
-		return nil, gg.Syntax("expected '=' after array index assignment expression\n%s", p.String())
-	}
-
-	val, err := parseValueExpr(p)
-	if err != nil {
-		return nil, err
-	}
-	if !advanceIfCurrIs(p, token.Term) {
-		return nil, gg.Syntax("expected ; after array index assignment\n%s", p.String())
-	}
-
-	return &ArrayIndexAssignmentExpression{
-		ArrayIndexExpression: arrayAccessExpr,
-		Value:                val,
-	}, nil
-}
-
 func arguments(p tokenParser, open, close token.Type) ([]ValueExpression, error) {
-	if !advanceIfCurrIs(p, open) {
-		return nil, gg.Syntax("expected '%s' after '%s'\n%s", close, open, p.String())
+	if err := expect(p, open, "expected '%s' to open argument list", open); err != nil {
+		return nil, err
 	}
+	defer p.setInGuard(false)()
 
 	var args []ValueExpression
 	if advanceIfCurrIs(p, close) {
@@ -568,7 +1225,13 @@ func arguments(p tokenParser, open, close token.Type) ([]ValueExpression, error)
 
 	for {
 		if p.HasCurr {
-			expr, err := parseValueExpr(p)
+			var expr ValueExpression
+			var err error
+			if p.Curr.TokenType == token.Ellipsis {
+				expr, err = parseSpreadExpr(p)
+			} else {
+				expr, err = parseValueExpr(p)
+			}
 			if err != nil {
 				return nil, err
 			}
@@ -582,25 +1245,126 @@ func arguments(p tokenParser, open, close token.Type) ([]ValueExpression, error)
 		}
 	}
 
-	if !advanceIfCurrIs(p, close) {
-		return nil, gg.Syntax("expected '%s' after last argument\n%s", close, p.String())
+	if err := expectListEnd(p, close, "expected '%s' or ',' after argument", close); err != nil {
+		return nil, err
 	}
 
 	return args, nil
 }
-func parseFuncCallExpr(id *Identifier, p tokenParser) (ValueExpression, error) {
-	args, err := arguments(p, token.OpenParen, token.CloseParen)
-	if err != nil {
+func parseFuncCallExpr(callee ValueExpression, p tokenParser) (*FunctionCallExpression, error) {
+	if err := expect(p, token.OpenParen, "expected '(' to open argument list"); err != nil {
 		return nil, err
 	}
+	defer p.setInGuard(false)()
+
+	call := &FunctionCallExpression{Callee: callee}
+	seen := make(map[string]bool)
+	for p.HasCurr && p.Curr.TokenType != token.CloseParen {
+		// named arguments are name: value
+		if p.Curr.TokenType == token.Ident && p.Next.TokenType == token.Colon {
+			name := p.Curr
+			p.Advance() // eat the name
+			p.Advance() // eat the colon
+			value, err := parseValueExpr(p)
+			if err != nil {
+				return nil, err
+			}
 
-	return &FunctionCallExpression{
-		Id:   id,
-		Args: args,
-	}, nil
+			if seen[name.Symbol] {
+				p.report(gg.SyntaxAt(name.Pos, "duplicate named argument %s in call to %s", name.Symbol, callee.Name()))
+			}
+			seen[name.Symbol] = true
+			call.NamedArgs = append(call.NamedArgs, NamedArg{Name: name, Value: value})
+		} else {
+			argStart := p.Curr
+			var value ValueExpression
+			var err error
+			if p.Curr.TokenType == token.Ellipsis {
+				value, err = parseSpreadExpr(p)
+			} else {
+				value, err = parseValueExpr(p)
+			}
+			if err != nil {
+				return nil, err
+			}
+			if len(call.NamedArgs) > 0 {
+				p.report(gg.SyntaxAt(argStart.Pos, "positional argument after named arguments in call to %s", callee.Name()))
+			}
+			call.Args = append(call.Args, value)
+		}
+
+		if !advanceIfCurrIs(p, token.Comma) {
+			break
+		}
+	}
+	if err := expectListEnd(p, token.CloseParen, "expected ')' or ',' after argument"); err != nil {
+		return nil, err
+	}
+
+	call.Span = span(p, callee.Pos())
+	return call, nil
+}
+
+// returns the span from start to the end of the last token the parser consumed
+func span(p tokenParser, start gg.Pos) Span {
+	end := start
+	if prev, ok := p.Prev(); ok {
+		end = prev.EndPos
+	}
+	return Span{Start: start, End: end}
 }
 
 // Advances the parser if the current token matches the given token type
 func advanceIfCurrIs(p tokenParser, tt token.Type) bool {
 	return p.AdvanceIf(func(t token.Token) bool { return t.TokenType == tt })
 }
+
+// advances past the current token if it is of the given type, otherwise returns a
+// syntax error with the message and the expected type
+func expect(p tokenParser, tt token.Type, msg string, args ...interface{}) error {
+	if advanceIfCurrIs(p, tt) {
+		return nil
+	}
+	return expecting(gg.Syntax(msg+"\n%s", append(args, p.String())...), tt)
+}
+
+// like expect, for the end of a list that could also go on after a comma
+func expectListEnd(p tokenParser, close token.Type, msg string, args ...interface{}) error {
+	if advanceIfCurrIs(p, close) {
+		return nil
+	}
+	return expecting(gg.Syntax(msg+"\n%s", append(args, p.String())...), close, token.Comma)
+}
+
+// sets the tokens that would have been valid where err was found
+func expecting(err *gg.SyntaxErr, types ...token.Type) *gg.SyntaxErr {
+	err.Expected = make([]string, len(types))
+	for i, tt := range types {
+		err.Expected[i] = describeType(tt)
+	}
+	return err
+}
+
+// '}' for token types with a fixed symbol, and their name for the rest, like identifier
+func describeType(tt token.Type) string {
+	if tt.HasSymbol() {
+		return "'" + tt.String() + "'"
+	}
+	return tt.String()
+}
+
+// the tokens an operand can start with, not counting prefix operators
+var operandStarts = []token.Type{
+	token.Ident, token.IntLiteral, token.FloatLiteral, token.StringLiteral, token.TemplateHead,
+	token.TrueLiteral, token.FalseLiteral, token.NilLiteral,
+	token.OpenParen, token.OpenBracket, token.OpenBrace, token.Function, token.Match, token.If,
+}
+
+// the tokens a value expression can start with
+var valueStarts = append(slices.Clone(operandStarts), token.Minus, token.LogicalNot, token.BitwiseNot)
+
+// the tokens that can follow the target of a simple statement
+var simpleStmtContinuations = []token.Type{
+	token.Assign, token.PlusAssign, token.MinusAssign, token.MulAssign, token.DivAssign, token.ModAssign,
+	token.Increment, token.Decrement, token.OpenParen,
+}
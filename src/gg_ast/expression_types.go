@@ -2,6 +2,8 @@
 
 package gg_ast
 
+import "gg-lang/src/gg"
+
 type ExpressionKind int
 
 const (
@@ -11,33 +13,45 @@ const (
 	ExprBinary ExpressionKind = iota
 	ExprUnary
 	ExprIntLiteral
+	ExprFloatLiteral
 	ExprBoolLiteral
 	ExprVariable
 	ExprStringLiteral
+	ExprNilLiteral
 	ExprFunctionCall
 	ExprObject
 	ExprArrayDecl
 	ExprArrayIndex
-	ExprArrayIndexAssignment
 	ExprDotAccess
 	ExprParenthesized
+	ExprTemplate
+	ExprMatch
+	ExprConditional
+	ExprSpread
+	ExprIllegal
 	SentinelValueExpression
 
 	/*
 	   Expression implementing kinds
 	*/
 	ExprAssignment
-	ExprDotAccessAssignment
+	ExprDestructuring
+	ExprDeclaration
 	ExprFuncDecl
 	ExprForLoop
+	ExprForIn
 	ExprIfElse
 	ExprBlock
 	ExprReturn
 	ExprTryCatch
+	ExprBreak
+	ExprContinue
 )
 
 type Expression interface {
 	Kind() ExpressionKind
+	// the position of the first token of the expression
+	Pos() gg.Pos
 }
 
 type ValueExpression interface {
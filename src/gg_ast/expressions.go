@@ -3,6 +3,7 @@ package gg_ast
 
 import (
 	"fmt"
+	"gg-lang/src/gg"
 	"gg-lang/src/token"
 	"strings"
 )
@@ -12,13 +13,16 @@ type IdExprKind int
 
 const (
 	IdExprNumber    = IdExprKind(ExprIntLiteral)
+	IdExprFloat     = IdExprKind(ExprFloatLiteral)
 	IdExprString    = IdExprKind(ExprStringLiteral)
 	IdExprBool      = IdExprKind(ExprBoolLiteral)
+	IdExprNil       = IdExprKind(ExprNilLiteral)
 	IdExprVariable  = IdExprKind(ExprVariable)
 	IdExprDotAccess = IdExprKind(ExprDotAccess)
 )
 
 type Literal struct {
+	Span
 	Tok token.Token
 }
 
@@ -34,8 +38,12 @@ func getLiteralKind(tok token.Token) ExpressionKind {
 		return ExprBoolLiteral
 	case token.IntLiteral:
 		return ExprIntLiteral
+	case token.FloatLiteral:
+		return ExprFloatLiteral
 	case token.StringLiteral:
 		return ExprStringLiteral
+	case token.NilLiteral:
+		return ExprNilLiteral
 	default:
 		panic(fmt.Sprintf("unknown token type: %v", tok.TokenType))
 	}
@@ -46,6 +54,7 @@ func (l *Literal) Kind() ExpressionKind {
 
 // a
 type Identifier struct {
+	Span
 	Tok    token.Token
 	idKind IdExprKind
 }
@@ -57,10 +66,14 @@ func (id *Identifier) Kind() ExpressionKind {
 	switch id.idKind {
 	case IdExprNumber:
 		return ExprIntLiteral
+	case IdExprFloat:
+		return ExprFloatLiteral
 	case IdExprString:
 		return ExprStringLiteral
 	case IdExprBool:
 		return ExprBoolLiteral
+	case IdExprNil:
+		return ExprNilLiteral
 	case IdExprVariable:
 		return ExprVariable
 	case IdExprDotAccess:
@@ -76,8 +89,17 @@ type BlockStatement []Expression
 func (bs BlockStatement) SetStatements(s []Expression) { copy(bs, s) }
 func (bs BlockStatement) Kind() ExpressionKind         { return ExprBlock }
 
+// a block has no tokens of its own in the tree, so it is positioned at its first statement
+func (bs BlockStatement) Pos() gg.Pos {
+	if len(bs) == 0 {
+		return gg.Pos{}
+	}
+	return bs[0].Pos()
+}
+
 // -b
 type UnaryExpression struct {
+	Span
 	Op  token.Token
 	Rhs ValueExpression
 }
@@ -87,14 +109,39 @@ func (be *UnaryExpression) Kind() ExpressionKind { return ExprUnary }
 
 // (a)
 type ParenthesizedExpression struct {
+	Span
 	Expr ValueExpression
 }
 
 func (pe *ParenthesizedExpression) Name() string         { return fmt.Sprintf("(%s)", pe.Expr.Name()) }
 func (pe *ParenthesizedExpression) Kind() ExpressionKind { return ExprParenthesized }
 
+// "a is ${a}"
+// Strings holds the literal text around each expression, so there is always
+// one more string than there are expressions
+type TemplateExpression struct {
+	Span
+	Strings []string
+	Exprs   []ValueExpression
+}
+
+func (te *TemplateExpression) Kind() ExpressionKind { return ExprTemplate }
+func (te *TemplateExpression) Name() string {
+	sb := &strings.Builder{}
+	sb.WriteString("\"")
+	for i, s := range te.Strings {
+		sb.WriteString(s)
+		if i < len(te.Exprs) {
+			sb.WriteString("${" + te.Exprs[i].Name() + "}")
+		}
+	}
+	sb.WriteString("\"")
+	return sb.String()
+}
+
 // a + b
 type BinaryExpression struct {
+	Span
 	Lhs ValueExpression
 	Op  token.Token
 	Rhs ValueExpression
@@ -103,17 +150,29 @@ type BinaryExpression struct {
 func (be *BinaryExpression) Name() string         { return be.Op.Symbol }
 func (be *BinaryExpression) Kind() ExpressionKind { return ExprBinary }
 
-// a(b, c)
+// a(b, c), obj.handler(b) or makeHandler()(b)
+// f(1, 2) or f(1, b: 2) with named arguments, which always come last
 type FunctionCallExpression struct {
-	Id   *Identifier
-	Args []ValueExpression
+	Span
+	Callee    ValueExpression
+	Args      []ValueExpression
+	NamedArgs []NamedArg
+	// f?.(), which is nil when f is nil
+	Optional bool
 }
 
-func (fce *FunctionCallExpression) Name() string         { return fce.Id.Name() }
+// b: 2 in f(1, b: 2)
+type NamedArg struct {
+	Name  token.Token
+	Value ValueExpression
+}
+
+func (fce *FunctionCallExpression) Name() string         { return fce.Callee.Name() }
 func (fce *FunctionCallExpression) Kind() ExpressionKind { return ExprFunctionCall }
 
 // try { a = 32 } catch (e) { print(e) }
 type TryCatchExpression struct {
+	Span
 	Try     *BlockStatement
 	Catch   *CatchExpression
 	Finally *BlockStatement
@@ -125,31 +184,73 @@ func (t TryCatchExpression) Kind() ExpressionKind {
 
 // catch (e) { print(e) }
 type CatchExpression struct {
+	Span
 	ErrorParam string
 	Body       *BlockStatement
 }
 
-// a = 32
+// a = 32, a.b = 5, a.items[2].name = "x", a += 1 or a.count++
 type AssignmentExpression struct {
-	Target *Identifier
-	Value  ValueExpression
+	Span
+	// an assignable expression, see IsAssignable
+	Target ValueExpression
+	// = or a compound operator like += or ++
+	Op token.Token
+	// nil for ++ and --
+	Value ValueExpression
 }
 
 func (ae *AssignmentExpression) Kind() ExpressionKind { return ExprAssignment }
 
-// a.b = 5
-type DotAccessAssignmentExpression struct {
-	Target *DotAccessExpression
-	Value  ValueExpression
+// [a, b] = pair, [head, ...tail] = list or {name, age} = user
+type DestructuringAssignment struct {
+	Span
+	Pattern Pattern
+	Value   ValueExpression
+}
+
+func (da *DestructuringAssignment) Kind() ExpressionKind { return ExprDestructuring }
+
+// let x = 1, let count;, const limit = 10 or let [a, b] = pair.
+// the names live until the end of the block they're declared in.
+type DeclarationStatement struct {
+	Span
+	Const   bool
+	Pattern Pattern
+	// nil for let x;, which declares x as void
+	Value ValueExpression
 }
 
-func (d *DotAccessAssignmentExpression) Kind() ExpressionKind { return ExprDotAccessAssignment }
+func (ds *DeclarationStatement) Kind() ExpressionKind { return ExprDeclaration }
+
+// only variables, properties and array elements can be assigned to, a?.b and a?.[i] can't
+func IsAssignable(expr ValueExpression) bool {
+	switch expr := expr.(type) {
+	case *DotAccessExpression:
+		return !expr.Optional
+	case *ArrayIndexExpression:
+		return !expr.Optional
+	}
+	return expr.Kind() == ExprVariable
+}
 
 // routine a(b, c) {
+// routine name(a, b) { }, routine (a, b) { } or (a, b) => a + b
 type FunctionDeclExpression struct {
-	Target *Identifier
-	Params []token.Token
-	Body   BlockStatement
+	Span
+	Target *Identifier // nil for anonymous routines
+	Params []Param
+	// the body of an arrow routine with an expression body is a return of that expression
+	Body BlockStatement
+}
+
+// a, b = 10, {name, age} or ...rest in routine f(a, b = 10, {name, age}, ...rest)
+type Param struct {
+	Name    token.Token     // empty for destructured params
+	Pattern Pattern         // set instead of Name for destructured params
+	Default ValueExpression // optional, evaluated at every call that leaves the param out
+	// a rest param is always last, it takes the remaining positional arguments as an array
+	IsRest bool
 }
 
 func (fde *FunctionDeclExpression) Kind() ExpressionKind { return ExprFuncDecl }
@@ -157,11 +258,34 @@ func (fde *FunctionDeclExpression) SetStatements(s []Expression) {
 	fde.Body = s
 }
 func (fde *FunctionDeclExpression) Name() string {
+	if fde.Target == nil {
+		return "anonymous routine"
+	}
 	return fde.Target.Name()
 }
 
+// ...arr in [...arr, 4], f(...args) or { ...obj }
+type SpreadExpression struct {
+	Span
+	Value ValueExpression
+}
+
+func (se *SpreadExpression) Kind() ExpressionKind { return ExprSpread }
+func (se *SpreadExpression) Name() string         { return "..." + se.Value.Name() }
+
+// source the scanner could not make sense of, like 0b102, in place of an operand.
+// it lets the rest of the statement be parsed, its error is the scanner's to report.
+type IllegalExpression struct {
+	Span
+	Tok token.Token
+}
+
+func (ie *IllegalExpression) Kind() ExpressionKind { return ExprIllegal }
+func (ie *IllegalExpression) Name() string         { return ie.Tok.Raw }
+
 // [1, 2, 3]
 type ArrayDeclExpression struct {
+	Span
 	Elements []ValueExpression
 }
 
@@ -174,31 +298,36 @@ func (a ArrayDeclExpression) Name() string {
 	return fmt.Sprintf("[%s]", strings.Join(elements, ", "))
 }
 
-// a[1]
+// a[1], a[1][2] or makeList()[1]
 type ArrayIndexExpression struct {
-	Array *Identifier
+	Span
+	Array ValueExpression
 	Index ValueExpression
-}
-
-// a[1] = 1
-type ArrayIndexAssignmentExpression struct {
-	*ArrayIndexExpression
-	Value ValueExpression
-}
-
-func (ai ArrayIndexAssignmentExpression) Kind() ExpressionKind { return ExprArrayIndexAssignment }
-func (ai ArrayIndexAssignmentExpression) Name() string {
-	return fmt.Sprintf("%s[%s] = %s", ai.Array.Name(), ai.Index.Name(), ai.Value.Name())
+	// a?.[i], which is nil when a is nil or has nothing at i
+	Optional bool
 }
 
 func (ai ArrayIndexExpression) Kind() ExpressionKind { return ExprArrayIndex }
 func (ai ArrayIndexExpression) Name() string {
+	if ai.Optional {
+		return fmt.Sprintf("%s?.[%s]", ai.Array.Name(), ai.Index.Name())
+	}
 	return fmt.Sprintf("%s[%s]", ai.Array.Name(), ai.Index.Name())
 }
 
-// { x: 1, y: 2, z: 3 }
+// { x: 1, "y z": 2, [key]: 3, x, greet(n) { }, ...defaults }
 type ObjectExpression struct {
-	Properties map[string]ValueExpression
+	Span
+	// in source order, which is the order they're evaluated and added to the object in
+	Properties []ObjectProperty
+}
+
+type ObjectProperty struct {
+	Key string // empty for computed keys and spreads
+	// the key of {[key]: value}, which is evaluated when the object is made
+	Computed ValueExpression
+	// a SpreadExpression for ...obj, and a routine for greet(n) { }
+	Value ValueExpression
 }
 
 func (o ObjectExpression) Kind() ExpressionKind {
@@ -209,8 +338,13 @@ func (o ObjectExpression) Name() string {
 	return "[object]"
 }
 
+// a.b, a.b.c is the access to c of the access to b of a
 type DotAccessExpression struct {
-	AccessChain []string
+	Span
+	Object   ValueExpression
+	Property string
+	// a?.b, which is nil when a is nil or has no property b
+	Optional bool
 }
 
 func (d DotAccessExpression) Kind() ExpressionKind {
@@ -218,24 +352,46 @@ func (d DotAccessExpression) Kind() ExpressionKind {
 }
 
 func (d DotAccessExpression) Name() string {
-	return strings.Join(d.AccessChain, ".")
+	if d.Optional {
+		return d.Object.Name() + "?." + d.Property
+	}
+	return d.Object.Name() + "." + d.Property
 }
 
 // if a == b { } else if a == c { } else { }
 type IfElseStatement struct {
+	Span
 	Condition      ValueExpression
 	Body           BlockStatement
 	ElseExpression Expression // optional
 }
 
 func (ife *IfElseStatement) Kind() ExpressionKind { return ExprIfElse }
+func (ife *IfElseStatement) Name() string         { return "if " + ife.Condition.Name() }
 func (ife *IfElseStatement) SetStatements(s []Expression) {
 	ife.Body = s
 }
 
-// for i != 10 {
-type ForLoopExpression struct {
+// cond ? a : b
+type ConditionalExpression struct {
+	Span
 	Condition ValueExpression
+	Then      ValueExpression
+	Else      ValueExpression
+}
+
+func (ce *ConditionalExpression) Kind() ExpressionKind { return ExprConditional }
+func (ce *ConditionalExpression) Name() string {
+	return ce.Condition.Name() + " ? " + ce.Then.Name() + " : " + ce.Else.Name()
+}
+
+// for i != 10 {, for i = 0; i < 10; i++ { or outer: for i != 10 { with a label
+type ForLoopExpression struct {
+	Span
+	Label     string          // optional
+	Init      Expression      // optional, runs once before the loop
+	Condition ValueExpression // optional in counted loops, where it defaults to true
+	Post      Expression      // optional, runs after every iteration
 	Body      BlockStatement
 }
 
@@ -244,12 +400,66 @@ func (fle *ForLoopExpression) SetStatements(s []Expression) {
 	fle.Body = s
 }
 
+// for x in arr {, for i, x in arr {, for ch in str {, for k, v in obj { or for {name} in users {
+type ForInExpression struct {
+	Span
+	Label string // optional
+	// the index or key, optional
+	Key string
+	// the element, or the key when iterating an object without Key
+	Value string
+	// set instead of Value when the element is destructured, like for [a, b] in pairs {
+	ValuePattern Pattern
+	Iterable     ValueExpression
+	Body         BlockStatement
+}
+
+func (fie *ForInExpression) Kind() ExpressionKind { return ExprForIn }
+func (fie *ForInExpression) SetStatements(s []Expression) {
+	fie.Body = s
+}
+
 type ReturnStatement struct {
+	Span
 	Value ValueExpression
 }
 
 func (rs *ReturnStatement) Kind() ExpressionKind { return ExprReturn }
 
+// break; or break outer;
+type BreakStatement struct {
+	Span
+	Label string // optional
+}
+
+func (bs *BreakStatement) Kind() ExpressionKind { return ExprBreak }
+
+// continue; or continue outer;
+type ContinueStatement struct {
+	Span
+	Label string // optional
+}
+
+func (cs *ContinueStatement) Kind() ExpressionKind { return ExprContinue }
+
+// match value { pattern if guard => body, ... }
+type MatchExpression struct {
+	Span
+	Subject ValueExpression
+	Arms    []*MatchArm
+}
+
+func (me *MatchExpression) Kind() ExpressionKind { return ExprMatch }
+func (me *MatchExpression) Name() string         { return "match " + me.Subject.Name() }
+
+type MatchArm struct {
+	Span
+	Pattern Pattern
+	Guard   ValueExpression // optional
+	// a value expression or a block statement
+	Body Expression
+}
+
 func ind(count int) string {
 	var spaces []rune
 	for i := 0; i < count*4; i++ {
@@ -259,6 +469,22 @@ func ind(count int) string {
 	return string(spaces)
 }
 
+// Parenthesize renders the shape of a value expression with every operation in parentheses,
+// so 1 + 2 * 3 is (1 + (2 * 3)) and -a ** b is (-(a ** b)). other expressions are rendered by name.
+func Parenthesize(e ValueExpression) string {
+	switch e := e.(type) {
+	case *BinaryExpression:
+		return "(" + Parenthesize(e.Lhs) + " " + e.Op.Symbol + " " + Parenthesize(e.Rhs) + ")"
+	case *UnaryExpression:
+		return "(" + e.Op.Symbol + Parenthesize(e.Rhs) + ")"
+	case *ParenthesizedExpression:
+		return Parenthesize(e.Expr)
+	case *ConditionalExpression:
+		return "(" + Parenthesize(e.Condition) + " ? " + Parenthesize(e.Then) + " : " + Parenthesize(e.Else) + ")"
+	}
+	return e.Name()
+}
+
 func NoBuilderExprString(e Expression) string {
 	sb := &strings.Builder{}
 	ExprString(e, 0, sb)
@@ -273,11 +499,21 @@ func ExprString(e Expression, d int, sb *strings.Builder) {
 
 	switch val := e.(type) {
 	case *AssignmentExpression:
-		w("assign of ")
-		ExprString(val.Value, d+1, sb)
+		w("assign (" + val.Op.Symbol + ") of ")
+		if val.Value != nil {
+			ExprString(val.Value, d+1, sb)
+		}
 		sb.WriteString("\n")
 		w(" to")
 		ExprString(val.Target, d+1, sb)
+	case *DestructuringAssignment:
+		w("destructuring assignment of ")
+		ExprString(val.Value, d+1, sb)
+	case *DeclarationStatement:
+		w("declaration of " + strings.Join(patternNames(val.Pattern), ", "))
+		if val.Value != nil {
+			ExprString(val.Value, d+1, sb)
+		}
 	case *BinaryExpression:
 		w("operation of ")
 		ExprString(val.Lhs, d+1, sb)
@@ -287,12 +523,17 @@ func ExprString(e Expression, d int, sb *strings.Builder) {
 	case *Identifier:
 		w("Ident " + val.idKind.String() + " " + val.Tok.Symbol + "\n")
 	case *FunctionCallExpression:
-		w("call to " + val.Id.Name())
+		w("call to " + val.Name())
 		for _, param := range val.Args {
 			ExprString(param, d+1, sb)
 		}
+		for _, named := range val.NamedArgs {
+			sb.WriteString("\n")
+			w(named.Name.Symbol + ":")
+			ExprString(named.Value, d+1, sb)
+		}
 	case *FunctionDeclExpression:
-		w("decl of " + val.Target.Name())
+		w("decl of " + val.Name())
 		w(" to do")
 		for _, expr := range val.Body {
 			ExprString(expr, d+1, sb)
@@ -308,18 +549,22 @@ func ExprString(e Expression, d int, sb *strings.Builder) {
 
 		w("} end block")
 	case *DotAccessExpression:
-		w("access to " + val.Name())
-	case *DotAccessAssignmentExpression:
-		w("assign of ")
-		ExprString(val.Value, d+1, sb)
-		sb.WriteString("\n")
-		w("to")
-		ExprString(val.Target, d+1, sb)
-		w(" (dot access)")
-		w("\n")
+		w("access to property " + val.Property + " of ")
+		ExprString(val.Object, d+1, sb)
 	case *ParenthesizedExpression:
 		w("parenthesized expression of ")
 		ExprString(val.Expr, d+1, sb)
+	case *TemplateExpression:
+		w("template of ")
+		for i, s := range val.Strings {
+			ExprString(&Identifier{Tok: token.Token{Symbol: s}, idKind: IdExprString}, d+1, sb)
+			if i < len(val.Exprs) {
+				ExprString(val.Exprs[i], d+1, sb)
+			}
+		}
+	case *SpreadExpression:
+		w("spread of ")
+		ExprString(val.Value, d+1, sb)
 	case *ArrayDeclExpression:
 		w("array declaration of ")
 		for i, expr := range val.Elements {
@@ -328,6 +573,75 @@ func ExprString(e Expression, d int, sb *strings.Builder) {
 				w(", ")
 			}
 		}
+	case *ReturnStatement:
+		w("return ")
+		if val.Value != nil {
+			ExprString(val.Value, d+1, sb)
+		}
+	case *ForLoopExpression:
+		w("counted loop")
+		for _, expr := range []Expression{val.Init, val.Condition, val.Post} {
+			if expr != nil {
+				ExprString(expr, d+1, sb)
+			}
+		}
+		ExprString(val.Body, d+1, sb)
+	case *ForInExpression:
+		w("for-in loop over ")
+		ExprString(val.Iterable, d+1, sb)
+		ExprString(val.Body, d+1, sb)
+	case *TryCatchExpression:
+		w("try")
+		ExprString(*val.Try, d+1, sb)
+		sb.WriteString("\n")
+		w("catch (" + val.Catch.ErrorParam + ")")
+		ExprString(*val.Catch.Body, d+1, sb)
+		if val.Finally != nil {
+			sb.WriteString("\n")
+			w("finally")
+			ExprString(*val.Finally, d+1, sb)
+		}
+	case *ObjectExpression:
+		w("object of ")
+		for _, prop := range val.Properties {
+			sb.WriteString("\n")
+			if prop.Computed != nil {
+				w("[" + prop.Computed.Name() + "]:")
+			} else if prop.Key != "" {
+				w(prop.Key + ":")
+			}
+			ExprString(prop.Value, d+1, sb)
+		}
+	case *BreakStatement:
+		w(strings.TrimSpace("break " + val.Label))
+	case *ContinueStatement:
+		w(strings.TrimSpace("continue " + val.Label))
+	case *IfElseStatement:
+		w("if ")
+		ExprString(val.Condition, d+1, sb)
+		ExprString(val.Body, d+1, sb)
+		if val.ElseExpression != nil {
+			sb.WriteString("\n")
+			w("else")
+			ExprString(val.ElseExpression, d+1, sb)
+		}
+	case *ConditionalExpression:
+		w("conditional of ")
+		ExprString(val.Condition, d+1, sb)
+		sb.WriteString("\n")
+		w(" then")
+		ExprString(val.Then, d+1, sb)
+		sb.WriteString("\n")
+		w(" else")
+		ExprString(val.Else, d+1, sb)
+	case *MatchExpression:
+		w("match of ")
+		ExprString(val.Subject, d+1, sb)
+		for _, arm := range val.Arms {
+			sb.WriteString("\n")
+			w(" arm")
+			ExprString(arm.Body, d+1, sb)
+		}
 	case *ArrayIndexExpression:
 		w("access to array index of ")
 		ExprString(val.Array, d+1, sb)
@@ -335,17 +649,12 @@ func ExprString(e Expression, d int, sb *strings.Builder) {
 		ExprString(val.Index, d+1, sb)
 		w("]")
 		w("\n")
-	case *ArrayIndexAssignmentExpression:
-		w("assign of ")
-		ExprString(val.Value, d+1, sb)
-		sb.WriteString("\n")
-		w("to")
-		ExprString(val.ArrayIndexExpression, d+1, sb)
-		w(" (array index assignment)")
-		w("\n")
 
+	// this is used in error messages, so it can't fail on a kind it doesn't know
+	case ValueExpression:
+		w(val.Name())
 	default:
-		panic(fmt.Sprintf("unknown expression type: %T", e))
+		w(fmt.Sprintf("%T", e))
 	}
 	return
 }
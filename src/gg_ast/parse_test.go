@@ -0,0 +1,175 @@
+package gg_ast
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrecedence(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"a - b - c", "((a - b) - c)"},
+		{"a / b * c", "((a / b) * c)"},
+		{"a ** b ** c", "(a ** (b ** c))"},
+		{"-a ** b", "(-(a ** b))"},
+		{"-a * b", "((-a) * b)"},
+		{"!a == b", "((!a) == b)"},
+		{"- -a", "(-(-a))"},
+		{"a + b * c ** d", "(a + (b * (c ** d)))"},
+		{"a == 1 && b == 2", "((a == 1) && (b == 2))"},
+		{"a && b || c && d", "((a && b) || (c && d))"},
+		{"a < b == c > d", "((a < b) == (c > d))"},
+		{"a + b << c", "((a + b) << c)"},
+		{"a | b ^ c & d", "(a | (b ^ (c & d)))"},
+		{"(a - b) - (c - d)", "((a - b) - (c - d))"},
+		{"a ?? b || c", "(a ?? (b || c))"},
+		{"a || b ? c : d ? e : f", "((a || b) ? c : (d ? e : f))"},
+		{"a ?.5 : b", "(a ? .5 : b)"},
+		{"a?.b ?? c", "(a?.b ?? c)"},
+	}
+	for _, tt := range tests {
+		expr, err := BuildValueFromString(tt.src)
+		if err != nil {
+			t.Errorf("%q: %v", tt.src, err)
+			continue
+		}
+		if got := Parenthesize(expr); got != tt.want {
+			t.Errorf("%q is %s, want %s", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		// lexical errors
+		{"1 + 0b102 * 2", "1:5: invalid digit '2' in binary literal 0b102"},
+		{"1 + @2", "1:5: unexpected character '@'"},
+		{`1 + "bad \q escape" + 2`, `1:10: unknown escape sequence \q`},
+		// syntax errors
+		{"1 + *", "1:5: unexpected operator * at the start of an expression (expected identifier, integer literal, float literal, string literal, string interpolation, 'true', 'false', 'nil', '(', '[', '{', 'routine', 'match', 'if', '-', '!' or '~')\n1 + "},
+		{"match [1, 2] { [x, x] => x, _ => 0 }", "1:16: x is bound twice in the same match pattern"},
+		{"match 1 { 0 => 0, {a, b: [a]} => a }", "1:19: a is bound twice in the same match pattern"},
+		{"match 5 { [b] | _ => b, _ => 0 }", "1:17: alternatives of a pattern must bind the same names, got [b] and []\nmatch 5 { [ b ] | _ => b , _ => 0 "},
+	}
+	for _, tt := range tests {
+		_, err := BuildValueFromString(tt.src)
+		if err == nil {
+			t.Errorf("%q parsed, want error %q", tt.src, tt.want)
+			continue
+		}
+		if err.Error() != tt.want {
+			t.Errorf("%q gave error %q, want %q", tt.src, err.Error(), tt.want)
+		}
+	}
+}
+
+func TestBlockValues(t *testing.T) {
+	// blocks used as values end with their value
+	valid := []string{
+		"let a = if true { 1 } else { 2 };",
+		"let b = match 1 { 1 => { let c = 2; c * 3 }, _ => 0 };",
+		"let d = if true { if false { 1 } else { match 2 { _ => { 3 } } } } else { 4 };",
+		"let e = if true { for x in [1] { print(x); } 5 } else { 6 };",
+	}
+	for _, src := range valid {
+		if _, err := BuildFromString(src); err != nil {
+			t.Errorf("%q: %v", src, err)
+		}
+	}
+
+	// other blocks can't, the value would be thrown away
+	invalid := []string{
+		"routine f() { 1 + 2 }",
+		"for x in [1] { x * 2 }",
+		"try { 5 } catch (e) { 6 }",
+		"if true { 1 }",
+		"let g = if true { for x in [1] { x } 7 } else { 8 };",
+	}
+	for _, src := range invalid {
+		_, err := BuildFromString(src)
+		if err == nil || !strings.Contains(err.Error(), "invalid top-level expression, only assignments and calls can be statements") {
+			t.Errorf("%q gave error %v, want an invalid top-level expression", src, err)
+		}
+	}
+}
+
+func TestExprString(t *testing.T) {
+	// dumps are used in error messages, so every kind of node has to be printable
+	src := `routine f(xs) {
+	for let i = 0; i < 3; i++ { print(i); }
+	for x in xs { print(x); }
+	try { print(1); } catch (e) { print(e); } finally { print(2); }
+	let o = {a: 1, ["b"]: 2, ...xs};
+	let s = [...xs];
+	let v = if true { 1 } else { 2 };
+	return match v { 1 => "one", _ => "other" };
+}`
+	ast, err := BuildFromString(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dump := NoBuilderExprString(ast.Body[0])
+	for _, want := range []string{"counted loop", "for-in loop over", "try", "catch (e)", "finally", "object of", "spread of", "if", "else", "return", "match of"} {
+		if !strings.Contains(dump, want) {
+			t.Errorf("dump is missing %q:%s", want, dump)
+		}
+	}
+	// kinds ExprString doesn't know are dumped by their Go type
+	if strings.Contains(dump, "*gg_ast.") {
+		t.Errorf("dump has a node ExprString doesn't describe:%s", dump)
+	}
+}
+
+func TestIllegalRecovery(t *testing.T) {
+	// stray characters are reported by the scanner, and the parser doesn't add errors after them
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"w = @ # 2;", "1:5: unexpected character '@'\n1:7: unexpected character '#'"},
+		{"w = 1 @ 2;", "1:7: unexpected character '@'"},
+		{"w = @ #;", "1:5: unexpected character '@'\n1:7: unexpected character '#'"},
+		{"w = 0b102 + 1;", "1:5: invalid digit '2' in binary literal 0b102"},
+	}
+	for _, tt := range tests {
+		_, err := BuildFromString(tt.src)
+		if err == nil {
+			t.Errorf("%q parsed, want error %q", tt.src, tt.want)
+			continue
+		}
+		if err.Error() != tt.want {
+			t.Errorf("%q gave error %q, want %q", tt.src, err.Error(), tt.want)
+		}
+	}
+}
+
+func TestDestructuringTargets(t *testing.T) {
+	if _, err := BuildFromString("[a[0][0], a[0][1]] = pair; {x: o.x, y: o[\"y\"] = 1} = p;"); err != nil {
+		t.Errorf("assigning to properties and elements: %v", err)
+	}
+
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"let [q.r] = [1];", "1:6: cannot declare q.r, only names can be declared"},
+		{"for [k.v] in [[1]] { }", "1:6: cannot declare k.v, only names can be declared"},
+		{"routine f({a: b[0]}) { }", "1:15: cannot declare b[0], only names can be declared"},
+		{"[f()] = [1];", "1:2: cannot assign to f, only variables, properties and array elements can be assigned to\n[ f ( ) ] = [ 1 ] "},
+	}
+	for _, tt := range tests {
+		_, err := BuildFromString(tt.src)
+		if err == nil {
+			t.Errorf("%q parsed, want error %q", tt.src, tt.want)
+			continue
+		}
+		if err.Error() != tt.want {
+			t.Errorf("%q gave error %q, want %q", tt.src, err.Error(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,370 @@
+package gg_ast
+
+import (
+	"gg-lang/src/gg"
+	"gg-lang/src/token"
+	"slices"
+	"strings"
+)
+
+// a Pattern is the left side of a match arm or of a destructuring assignment,
+// it checks the shape of a value and binds the parts of it that have a name
+type Pattern interface {
+	Pos() gg.Pos
+	isPattern()
+}
+
+// 1, "text", true or -2.5, matches values equal to it
+type LiteralPattern struct {
+	Span
+	Value ValueExpression
+}
+
+// x, matches anything and binds it to x
+type BindingPattern struct {
+	Span
+	Name string
+}
+
+// _, matches anything
+type WildcardPattern struct {
+	Span
+}
+
+// [first, second, ...rest]
+type ArrayPattern struct {
+	Span
+	Elements []Pattern
+	// whether the pattern ends with ...rest, which matches any remaining elements
+	HasRest bool
+	// the name the remaining elements are bound to, empty for ..._
+	Rest string
+}
+
+// {kind: "user", name}, name is short for name: name
+type ObjectPattern struct {
+	Span
+	Properties []PropertyPattern
+}
+
+type PropertyPattern struct {
+	Key     string
+	Pattern Pattern
+}
+
+// 1 | 2 | 3, matches when any of the alternatives does
+type OrPattern struct {
+	Span
+	Alternatives []Pattern
+}
+
+// b = 2 in [a, b = 2] = pair, the default is used when the value is missing.
+// only destructuring patterns have defaults.
+type DefaultPattern struct {
+	Span
+	Pattern Pattern
+	Default ValueExpression
+}
+
+// a.b or a[0] in [a.b, a[0]] = pair, stores the value in a property or an array element.
+// only destructuring assignments have targets, declarations can only bind names.
+type TargetPattern struct {
+	Span
+	Target ValueExpression
+}
+
+func (*LiteralPattern) isPattern()  {}
+func (*BindingPattern) isPattern()  {}
+func (*WildcardPattern) isPattern() {}
+func (*ArrayPattern) isPattern()    {}
+func (*ObjectPattern) isPattern()   {}
+func (*OrPattern) isPattern()       {}
+func (*DefaultPattern) isPattern()  {}
+func (*TargetPattern) isPattern()   {}
+
+// the names a pattern binds, in the order they appear
+func patternNames(pat Pattern) []string {
+	switch pat := pat.(type) {
+	case *BindingPattern:
+		return []string{pat.Name}
+	case *DefaultPattern:
+		return patternNames(pat.Pattern)
+	case *ArrayPattern:
+		var names []string
+		for _, elem := range pat.Elements {
+			names = append(names, patternNames(elem)...)
+		}
+		if pat.Rest != "" {
+			names = append(names, pat.Rest)
+		}
+		return names
+	case *ObjectPattern:
+		var names []string
+		for _, prop := range pat.Properties {
+			names = append(names, patternNames(prop.Pattern)...)
+		}
+		return names
+	case *OrPattern:
+		// every alternative binds the same names
+		return patternNames(pat.Alternatives[0])
+	}
+	return nil
+}
+
+// the first property or element target in pat, nil if it only binds names
+func firstTarget(pat Pattern) *TargetPattern {
+	switch pat := pat.(type) {
+	case *TargetPattern:
+		return pat
+	case *DefaultPattern:
+		return firstTarget(pat.Pattern)
+	case *ArrayPattern:
+		for _, elem := range pat.Elements {
+			if t := firstTarget(elem); t != nil {
+				return t
+			}
+		}
+	case *ObjectPattern:
+		for _, prop := range pat.Properties {
+			if t := firstTarget(prop.Pattern); t != nil {
+				return t
+			}
+		}
+	}
+	return nil
+}
+
+// reports the targets in a pattern that declares names, like the a.b in let [a.b] = pair
+func checkDeclarable(p tokenParser, pat Pattern) {
+	if t := firstTarget(pat); t != nil {
+		p.report(gg.SyntaxAt(t.Pos(), "cannot declare %s, only names can be declared", t.Target.Name()))
+	}
+}
+
+func sortedNames(pat Pattern) []string {
+	names := patternNames(pat)
+	slices.Sort(names)
+	return names
+}
+
+func parsePattern(p tokenParser) (Pattern, error) {
+	start := p.Curr
+	first, err := parsePrimaryPattern(p)
+	if err != nil {
+		return nil, err
+	}
+	if p.Curr.TokenType != token.BitwiseOr {
+		return first, nil
+	}
+
+	or := &OrPattern{Alternatives: []Pattern{first}}
+	for advanceIfCurrIs(p, token.BitwiseOr) {
+		alt, err := parsePrimaryPattern(p)
+		if err != nil {
+			return nil, err
+		}
+		// the arm body can't know which alternative matched, so each one binds the same names
+		want, got := sortedNames(first), sortedNames(alt)
+		if !slices.Equal(want, got) {
+			return nil, gg.SyntaxAt(alt.Pos(), "alternatives of a pattern must bind the same names, got [%s] and [%s]\n%s",
+				strings.Join(want, ", "), strings.Join(got, ", "), p.String())
+		}
+		or.Alternatives = append(or.Alternatives, alt)
+	}
+	or.Span = span(p, start.Pos)
+	return or, nil
+}
+
+func parsePrimaryPattern(p tokenParser) (Pattern, error) {
+	start := p.Curr
+	switch start.TokenType {
+	case token.Ident:
+		p.Advance()
+		if start.Symbol == "_" {
+			return &WildcardPattern{Span: span(p, start.Pos)}, nil
+		}
+		return &BindingPattern{Span: span(p, start.Pos), Name: start.Symbol}, nil
+	case token.IntLiteral, token.FloatLiteral, token.StringLiteral, token.TrueLiteral, token.FalseLiteral, token.NilLiteral:
+		lit, err := parseIdentifier(p)
+		if err != nil {
+			return nil, err
+		}
+		return &LiteralPattern{Span: lit.Span, Value: lit}, nil
+	case token.Minus:
+		// negative numbers
+		p.Advance()
+		if p.Curr.TokenType != token.IntLiteral && p.Curr.TokenType != token.FloatLiteral {
+			return nil, gg.Syntax("expected a number after '-' in pattern\n%s", p.String())
+		}
+		lit, err := parseIdentifier(p)
+		if err != nil {
+			return nil, err
+		}
+		neg := &UnaryExpression{Span: span(p, start.Pos), Op: start, Rhs: lit}
+		return &LiteralPattern{Span: neg.Span, Value: neg}, nil
+	case token.OpenBracket:
+		return parseArrayPattern(p, false)
+	case token.OpenBrace:
+		return parseObjectPattern(p, false)
+	}
+
+	return nil, expecting(gg.Syntax("expected a pattern, got %s\n%s", start.Symbol, p.String()),
+		token.Ident, token.IntLiteral, token.FloatLiteral, token.StringLiteral, token.TrueLiteral, token.FalseLiteral,
+		token.NilLiteral, token.Minus, token.OpenBracket, token.OpenBrace)
+}
+
+// parses the target of a destructuring assignment, a destructured routine param or the
+// element of a for-in loop. unlike a match these can't fail on the contents of a value,
+// so they only hold names, _, and array and object patterns whose elements may have defaults.
+func parseDestructuringPattern(p tokenParser) (Pattern, error) {
+	start := p.Curr
+	switch start.TokenType {
+	case token.Ident:
+		p.Advance()
+		switch p.Curr.TokenType {
+		case token.Dot, token.OpenBracket, token.OpenParen, token.OptionalChain:
+			return parseTargetPattern(p, start)
+		}
+		if start.Symbol == "_" {
+			return &WildcardPattern{Span: span(p, start.Pos)}, nil
+		}
+		return &BindingPattern{Span: span(p, start.Pos), Name: start.Symbol}, nil
+	case token.OpenBracket:
+		return parseArrayPattern(p, true)
+	case token.OpenBrace:
+		return parseObjectPattern(p, true)
+	}
+
+	return nil, expecting(gg.Syntax("expected a name, an array pattern or an object pattern, got %s\n%s", start.Symbol, p.String()),
+		token.Ident, token.OpenBracket, token.OpenBrace)
+}
+
+// a property or element to store the value in, name is the variable it starts with
+func parseTargetPattern(p tokenParser, name token.Token) (Pattern, error) {
+	id := &Identifier{Span: Span{Start: name.Pos, End: name.EndPos}, Tok: name, idKind: IdExprVariable}
+	target, err := parsePostfixExpr(id, p)
+	if err != nil {
+		return nil, err
+	}
+	if !IsAssignable(target) {
+		return nil, gg.SyntaxAt(target.Pos(), "cannot assign to %s, only variables, properties and array elements can be assigned to\n%s", target.Name(), p.String())
+	}
+	return &TargetPattern{Span: span(p, name.Pos), Target: target}, nil
+}
+
+// an element of an array or object pattern that's being destructured, with an optional default
+func parseDestructuringElem(p tokenParser) (Pattern, error) {
+	start := p.Curr
+	pat, err := parseDestructuringPattern(p)
+	if err != nil {
+		return nil, err
+	}
+	return withDefault(p, pat, start)
+}
+
+// wraps pat in a DefaultPattern if it's followed by = and a default
+func withDefault(p tokenParser, pat Pattern, start token.Token) (Pattern, error) {
+	if !advanceIfCurrIs(p, token.Assign) {
+		return pat, nil
+	}
+	def, err := parseValueExpr(p)
+	if err != nil {
+		return nil, err
+	}
+	return &DefaultPattern{Span: span(p, start.Pos), Pattern: pat, Default: def}, nil
+}
+
+// elements of destructuring patterns are destructuring patterns too, with optional defaults
+func parseArrayPattern(p tokenParser, destructuring bool) (*ArrayPattern, error) {
+	elemParser := parsePattern
+	if destructuring {
+		elemParser = parseDestructuringElem
+	}
+
+	start := p.Curr
+	p.Advance() // eat the [
+
+	res := &ArrayPattern{}
+	for p.HasCurr && p.Curr.TokenType != token.CloseBracket {
+		if advanceIfCurrIs(p, token.Ellipsis) {
+			rest := p.Curr
+			if err := expect(p, token.Ident, "expected a name after '...' in array pattern"); err != nil {
+				return nil, err
+			}
+			res.HasRest = true
+			if rest.Symbol != "_" {
+				res.Rest = rest.Symbol
+			}
+			if p.Curr.TokenType == token.Dot || p.Curr.TokenType == token.OpenBracket {
+				return nil, gg.Syntax("the rest of an array pattern can only be stored in a name, not a property or an element\n%s", p.String())
+			}
+			if p.Curr.TokenType != token.CloseBracket {
+				return nil, gg.Syntax("...%s must be the last element of an array pattern\n%s", rest.Symbol, p.String())
+			}
+			break
+		}
+
+		elem, err := elemParser(p)
+		if err != nil {
+			return nil, err
+		}
+		res.Elements = append(res.Elements, elem)
+		if !advanceIfCurrIs(p, token.Comma) {
+			break
+		}
+	}
+	if err := expectListEnd(p, token.CloseBracket, "expected ']' or ',' in array pattern"); err != nil {
+		return nil, err
+	}
+
+	res.Span = span(p, start.Pos)
+	return res, nil
+}
+
+func parseObjectPattern(p tokenParser, destructuring bool) (*ObjectPattern, error) {
+	elemParser := parsePattern
+	if destructuring {
+		elemParser = parseDestructuringElem
+	}
+
+	start := p.Curr
+	p.Advance() // eat the {
+
+	res := &ObjectPattern{}
+	for p.HasCurr && p.Curr.TokenType != token.CloseBrace {
+		key := p.Curr
+		if err := expect(p, token.Ident, "expected property name in object pattern"); err != nil {
+			return nil, err
+		}
+
+		prop := PropertyPattern{Key: key.Symbol}
+		if advanceIfCurrIs(p, token.Colon) {
+			pattern, err := elemParser(p)
+			if err != nil {
+				return nil, err
+			}
+			prop.Pattern = pattern
+		} else {
+			prop.Pattern = &BindingPattern{Span: Span{Start: key.Pos, End: key.EndPos}, Name: key.Symbol}
+			// {name = "anon"} is short for {name: name = "anon"}
+			if destructuring {
+				pattern, err := withDefault(p, prop.Pattern, key)
+				if err != nil {
+					return nil, err
+				}
+				prop.Pattern = pattern
+			}
+		}
+		res.Properties = append(res.Properties, prop)
+
+		if !advanceIfCurrIs(p, token.Comma) {
+			break
+		}
+	}
+	if err := expectListEnd(p, token.CloseBrace, "expected '}' or ',' in object pattern"); err != nil {
+		return nil, err
+	}
+
+	res.Span = span(p, start.Pos)
+	return res, nil
+}
@@ -6,8 +6,8 @@ import (
 
 type equalsAlwaysTrue struct{}
 
-func (e *equalsAlwaysTrue) Evaluate(_, _ interface{}) interface{} {
-	return true
+func (e *equalsAlwaysTrue) Evaluate(_, _ interface{}) (interface{}, error) {
+	return true, nil
 }
 func (e *equalsAlwaysTrue) ResultType() variable.VarType {
 	return variable.Boolean
@@ -15,8 +15,8 @@ func (e *equalsAlwaysTrue) ResultType() variable.VarType {
 
 type equalsAlwaysFalse struct{}
 
-func (e *equalsAlwaysFalse) Evaluate(_, _ interface{}) interface{} {
-	return false
+func (e *equalsAlwaysFalse) Evaluate(_, _ interface{}) (interface{}, error) {
+	return false, nil
 }
 func (e *equalsAlwaysFalse) ResultType() variable.VarType {
 	return variable.Boolean
@@ -24,8 +24,8 @@ func (e *equalsAlwaysFalse) ResultType() variable.VarType {
 
 type genEquals struct{}
 
-func (g *genEquals) Evaluate(left, right interface{}) interface{} {
-	return left == right
+func (g *genEquals) Evaluate(left, right interface{}) (interface{}, error) {
+	return left == right, nil
 }
 
 func (g *genEquals) ResultType() variable.VarType {
@@ -34,8 +34,8 @@ func (g *genEquals) ResultType() variable.VarType {
 
 type genNotEquals struct{}
 
-func (g *genNotEquals) Evaluate(left, right interface{}) interface{} {
-	return left != right
+func (g *genNotEquals) Evaluate(left, right interface{}) (interface{}, error) {
+	return left != right, nil
 }
 func (g *genNotEquals) ResultType() variable.VarType {
 	return variable.Boolean
@@ -43,8 +43,8 @@ func (g *genNotEquals) ResultType() variable.VarType {
 
 type andBools struct{}
 
-func (a *andBools) Evaluate(lhs interface{}, rhs interface{}) interface{} {
-	return lhs.(bool) && rhs.(bool)
+func (a *andBools) Evaluate(lhs interface{}, rhs interface{}) (interface{}, error) {
+	return lhs.(bool) && rhs.(bool), nil
 }
 func (a *andBools) ResultType() variable.VarType {
 	return variable.Boolean
@@ -52,8 +52,8 @@ func (a *andBools) ResultType() variable.VarType {
 
 type orBools struct{}
 
-func (o *orBools) Evaluate(lhs interface{}, rhs interface{}) interface{} {
-	return lhs.(bool) || rhs.(bool)
+func (o *orBools) Evaluate(lhs interface{}, rhs interface{}) (interface{}, error) {
+	return lhs.(bool) || rhs.(bool), nil
 }
 func (o *orBools) ResultType() variable.VarType {
 	return variable.Boolean
@@ -61,8 +61,8 @@ func (o *orBools) ResultType() variable.VarType {
 
 type equalsBools struct{}
 
-func (e *equalsBools) Evaluate(lhs interface{}, rhs interface{}) interface{} {
-	return lhs.(bool) == rhs.(bool)
+func (e *equalsBools) Evaluate(lhs interface{}, rhs interface{}) (interface{}, error) {
+	return lhs.(bool) == rhs.(bool), nil
 }
 func (e *equalsBools) ResultType() variable.VarType {
 	return variable.Boolean
@@ -70,8 +70,8 @@ func (e *equalsBools) ResultType() variable.VarType {
 
 type notEqualsBools struct{}
 
-func (n *notEqualsBools) Evaluate(lhs interface{}, rhs interface{}) interface{} {
-	return lhs.(bool) != rhs.(bool)
+func (n *notEqualsBools) Evaluate(lhs interface{}, rhs interface{}) (interface{}, error) {
+	return lhs.(bool) != rhs.(bool), nil
 }
 func (n *notEqualsBools) ResultType() variable.VarType {
 	return variable.Boolean
@@ -80,8 +80,8 @@ func (n *notEqualsBools) ResultType() variable.VarType {
 // !bool
 type notBool struct{}
 
-func (n *notBool) Evaluate(input interface{}) interface{} {
-	return !input.(bool)
+func (n *notBool) Evaluate(input interface{}) (interface{}, error) {
+	return !input.(bool), nil
 }
 func (n *notBool) ResultType() variable.VarType {
 	return variable.Boolean
@@ -0,0 +1,112 @@
+package operators
+
+import (
+	"gg-lang/src/variable"
+	"math"
+)
+
+// float + float
+type plusFloats struct{}
+
+func (p *plusFloats) Evaluate(left, right interface{}) (interface{}, error) {
+	return left.(float64) + right.(float64), nil
+}
+func (p *plusFloats) ResultType() variable.VarType { return variable.Float }
+
+// float - float
+type minusFloats struct{}
+
+func (m *minusFloats) Evaluate(left, right interface{}) (interface{}, error) {
+	return left.(float64) - right.(float64), nil
+}
+func (m *minusFloats) ResultType() variable.VarType { return variable.Float }
+
+// float * float
+type mulFloats struct{}
+
+func (m *mulFloats) Evaluate(left, right interface{}) (interface{}, error) {
+	return left.(float64) * right.(float64), nil
+}
+func (m *mulFloats) ResultType() variable.VarType { return variable.Float }
+
+// float / float
+type divFloats struct{}
+
+func (d *divFloats) Evaluate(left, right interface{}) (interface{}, error) {
+	return left.(float64) / right.(float64), nil
+}
+func (d *divFloats) ResultType() variable.VarType { return variable.Float }
+
+// float < float
+type lessThanFloats struct{}
+
+func (l *lessThanFloats) Evaluate(left, right interface{}) (interface{}, error) {
+	return left.(float64) < right.(float64), nil
+}
+func (l *lessThanFloats) ResultType() variable.VarType { return variable.Boolean }
+
+// float > float
+type greaterThanFloats struct{}
+
+func (g *greaterThanFloats) Evaluate(left, right interface{}) (interface{}, error) {
+	return left.(float64) > right.(float64), nil
+}
+func (g *greaterThanFloats) ResultType() variable.VarType { return variable.Boolean }
+
+// float <= float
+type lessThanEqualFloats struct{}
+
+func (l *lessThanEqualFloats) Evaluate(left, right interface{}) (interface{}, error) {
+	return left.(float64) <= right.(float64), nil
+}
+func (l *lessThanEqualFloats) ResultType() variable.VarType { return variable.Boolean }
+
+// float >= float
+type greaterThanEqualFloats struct{}
+
+func (g *greaterThanEqualFloats) Evaluate(left, right interface{}) (interface{}, error) {
+	return left.(float64) >= right.(float64), nil
+}
+func (g *greaterThanEqualFloats) ResultType() variable.VarType { return variable.Boolean }
+
+// -float
+type minusFloat struct{}
+
+func (m *minusFloat) Evaluate(right interface{}) (interface{}, error) {
+	return -right.(float64), nil
+}
+func (m *minusFloat) ResultType() variable.VarType { return variable.Float }
+
+// int op float and float op int, the int side is promoted to a float before
+// the float operator is applied
+type promoteToFloat struct {
+	op Operator
+}
+
+func (p *promoteToFloat) Evaluate(left, right interface{}) (interface{}, error) {
+	return p.op.Evaluate(toFloat(left), toFloat(right))
+}
+func (p *promoteToFloat) ResultType() variable.VarType { return p.op.ResultType() }
+
+func toFloat(val interface{}) float64 {
+	if i, ok := val.(int64); ok {
+		return float64(i)
+	}
+	return val.(float64)
+}
+
+// float % float
+type modFloats struct{}
+
+func (m *modFloats) Evaluate(left, right interface{}) (interface{}, error) {
+	return math.Mod(left.(float64), right.(float64)), nil
+}
+func (m *modFloats) ResultType() variable.VarType { return variable.Float }
+
+// float ** float
+type powFloats struct{}
+
+func (p *powFloats) Evaluate(left, right interface{}) (interface{}, error) {
+	return math.Pow(left.(float64), right.(float64)), nil
+}
+func (p *powFloats) ResultType() variable.VarType { return variable.Float }
@@ -1,14 +1,24 @@
 package operators
 
 import (
+	"gg-lang/src/gg"
 	"gg-lang/src/variable"
+	"math"
 )
 
+// integers are 64-bit and every operator that can leave that range raises
+// a runtime error instead of wrapping around
+
 // int + int
 type plusInts struct{}
 
-func (p *plusInts) Evaluate(left, right interface{}) interface{} {
-	return left.(int) + right.(int)
+func (p *plusInts) Evaluate(left, right interface{}) (interface{}, error) {
+	l, r := left.(int64), right.(int64)
+	res := l + r
+	if (l > 0 && r > 0 && res < 0) || (l < 0 && r < 0 && res >= 0) {
+		return nil, gg.Runtime("integer overflow: %d + %d", l, r)
+	}
+	return res, nil
 }
 
 func (p *plusInts) ResultType() variable.VarType {
@@ -18,23 +28,41 @@ func (p *plusInts) ResultType() variable.VarType {
 // int - int
 type minusInts struct{}
 
-func (m *minusInts) Evaluate(left, right interface{}) interface{} {
-	return left.(int) - right.(int)
+func (m *minusInts) Evaluate(left, right interface{}) (interface{}, error) {
+	l, r := left.(int64), right.(int64)
+	res := l - r
+	if (r < 0 && res < l) || (r > 0 && res > l) {
+		return nil, gg.Runtime("integer overflow: %d - %d", l, r)
+	}
+	return res, nil
 }
 
 func (m *minusInts) ResultType() variable.VarType {
 	return variable.Integer
 }
 
-func (m *minusInts) UnaryEvaluate(right interface{}) interface{} {
-	return -right.(int)
-}
-
 // int * int
 type mulInts struct{}
 
-func (m *mulInts) Evaluate(left, right interface{}) interface{} {
-	return left.(int) * right.(int)
+func (m *mulInts) Evaluate(left, right interface{}) (interface{}, error) {
+	l, r := left.(int64), right.(int64)
+	res, ok := mulInt64(l, r)
+	if !ok {
+		return nil, gg.Runtime("integer overflow: %d * %d", l, r)
+	}
+	return res, nil
+}
+
+// multiplies two integers, ok is false if the result overflows
+func mulInt64(l, r int64) (int64, bool) {
+	if l == 0 || r == 0 {
+		return 0, true
+	}
+	res := l * r
+	if res/r != l || (l == -1 && r == math.MinInt64) || (r == -1 && l == math.MinInt64) {
+		return 0, false
+	}
+	return res, true
 }
 
 func (m *mulInts) ResultType() variable.VarType {
@@ -44,8 +72,15 @@ func (m *mulInts) ResultType() variable.VarType {
 // int / int
 type divInts struct{}
 
-func (d *divInts) Evaluate(left, right interface{}) interface{} {
-	return left.(int) / right.(int)
+func (d *divInts) Evaluate(left, right interface{}) (interface{}, error) {
+	l, r := left.(int64), right.(int64)
+	if r == 0 {
+		return nil, gg.Runtime("integer division by zero: %d / %d", l, r)
+	}
+	if l == math.MinInt64 && r == -1 {
+		return nil, gg.Runtime("integer overflow: %d / %d", l, r)
+	}
+	return l / r, nil
 }
 
 func (d *divInts) ResultType() variable.VarType {
@@ -55,39 +90,149 @@ func (d *divInts) ResultType() variable.VarType {
 // int < int
 type lessThanInts struct{}
 
-func (l *lessThanInts) Evaluate(left, right interface{}) interface{} {
-	return left.(int) < right.(int)
+func (l *lessThanInts) Evaluate(left, right interface{}) (interface{}, error) {
+	return left.(int64) < right.(int64), nil
 }
 func (l *lessThanInts) ResultType() variable.VarType { return variable.Boolean }
 
 // int > int
 type greaterThanInts struct{}
 
-func (g *greaterThanInts) Evaluate(left, right interface{}) interface{} {
-	return left.(int) > right.(int)
+func (g *greaterThanInts) Evaluate(left, right interface{}) (interface{}, error) {
+	return left.(int64) > right.(int64), nil
 }
 func (g *greaterThanInts) ResultType() variable.VarType { return variable.Boolean }
 
 // int <= int
 type lessThanEqualInts struct{}
 
-func (l *lessThanEqualInts) Evaluate(left, right interface{}) interface{} {
-	return left.(int) <= right.(int)
+func (l *lessThanEqualInts) Evaluate(left, right interface{}) (interface{}, error) {
+	return left.(int64) <= right.(int64), nil
 }
 func (l *lessThanEqualInts) ResultType() variable.VarType { return variable.Boolean }
 
 // int >= int
 type greaterThanEqualInts struct{}
 
-func (g *greaterThanEqualInts) Evaluate(left, right interface{}) interface{} {
-	return left.(int) >= right.(int)
+func (g *greaterThanEqualInts) Evaluate(left, right interface{}) (interface{}, error) {
+	return left.(int64) >= right.(int64), nil
 }
 func (g *greaterThanEqualInts) ResultType() variable.VarType { return variable.Boolean }
 
 // -int
 type minusInt struct{}
 
-func (m *minusInt) Evaluate(right interface{}) interface{} {
-	return -right.(int)
+func (m *minusInt) Evaluate(right interface{}) (interface{}, error) {
+	r := right.(int64)
+	if r == math.MinInt64 {
+		return nil, gg.Runtime("integer overflow: -(%d)", r)
+	}
+	return -r, nil
 }
 func (m *minusInt) ResultType() variable.VarType { return variable.Integer }
+
+// int % int
+type modInts struct{}
+
+func (m *modInts) Evaluate(left, right interface{}) (interface{}, error) {
+	l, r := left.(int64), right.(int64)
+	if r == 0 {
+		return nil, gg.Runtime("integer division by zero: %d %% %d", l, r)
+	}
+	if r == -1 {
+		// MinInt64 % -1 overflows in the division the remainder comes from
+		return int64(0), nil
+	}
+	return l % r, nil
+}
+func (m *modInts) ResultType() variable.VarType { return variable.Integer }
+
+// int ** int
+type powInts struct{}
+
+func (p *powInts) Evaluate(left, right interface{}) (interface{}, error) {
+	base, exp := left.(int64), right.(int64)
+	if exp < 0 {
+		return nil, gg.Runtime("negative integer exponent: %d ** %d", base, exp)
+	}
+
+	// exponentiation by squaring. the base is only squared when there are
+	// exponent bits left, so an overflowing square means the result overflows too
+	res, sq := int64(1), base
+	ok := true
+	for e := exp; e > 0 && ok; e >>= 1 {
+		if e&1 == 1 {
+			res, ok = mulInt64(res, sq)
+		}
+		if ok && e > 1 {
+			sq, ok = mulInt64(sq, sq)
+		}
+	}
+	if !ok {
+		return nil, gg.Runtime("integer overflow: %d ** %d", base, exp)
+	}
+	return res, nil
+}
+func (p *powInts) ResultType() variable.VarType { return variable.Integer }
+
+// int << int
+type shiftLeftInts struct{}
+
+func (s *shiftLeftInts) Evaluate(left, right interface{}) (interface{}, error) {
+	l, r := left.(int64), right.(int64)
+	if r < 0 {
+		return nil, gg.Runtime("negative shift count: %d << %d", l, r)
+	}
+	if r >= 64 || (l<<r)>>r != l {
+		if l != 0 {
+			return nil, gg.Runtime("integer overflow: %d << %d", l, r)
+		}
+		return int64(0), nil
+	}
+	return l << r, nil
+}
+func (s *shiftLeftInts) ResultType() variable.VarType { return variable.Integer }
+
+// int >> int
+type shiftRightInts struct{}
+
+func (s *shiftRightInts) Evaluate(left, right interface{}) (interface{}, error) {
+	l, r := left.(int64), right.(int64)
+	if r < 0 {
+		return nil, gg.Runtime("negative shift count: %d >> %d", l, r)
+	}
+	return l >> min(r, 63), nil
+}
+func (s *shiftRightInts) ResultType() variable.VarType { return variable.Integer }
+
+// int & int
+type andInts struct{}
+
+func (a *andInts) Evaluate(left, right interface{}) (interface{}, error) {
+	return left.(int64) & right.(int64), nil
+}
+func (a *andInts) ResultType() variable.VarType { return variable.Integer }
+
+// int | int
+type orInts struct{}
+
+func (o *orInts) Evaluate(left, right interface{}) (interface{}, error) {
+	return left.(int64) | right.(int64), nil
+}
+func (o *orInts) ResultType() variable.VarType { return variable.Integer }
+
+// int ^ int
+type xorInts struct{}
+
+func (x *xorInts) Evaluate(left, right interface{}) (interface{}, error) {
+	return left.(int64) ^ right.(int64), nil
+}
+func (x *xorInts) ResultType() variable.VarType { return variable.Integer }
+
+// ~int
+type notInt struct{}
+
+func (n *notInt) Evaluate(right interface{}) (interface{}, error) {
+	return ^right.(int64), nil
+}
+func (n *notInt) ResultType() variable.VarType { return variable.Integer }
@@ -7,12 +7,12 @@ import (
 )
 
 type Operator interface {
-	Evaluate(left, right interface{}) interface{}
+	Evaluate(left, right interface{}) (interface{}, error)
 	ResultType() variable.VarType
 }
 
 type UnaryOperator interface {
-	Evaluate(right interface{}) interface{}
+	Evaluate(right interface{}) (interface{}, error)
 	ResultType() variable.VarType
 }
 
@@ -74,6 +74,8 @@ func DefaultUnary() *UnaryOpMap {
 	}
 
 	opm.setUnary("-", variable.Integer, &minusInt{})
+	opm.setUnary("-", variable.Float, &minusFloat{})
+	opm.setUnary("~", variable.Integer, &notInt{})
 	opm.setUnary("!", variable.Boolean, &notBool{})
 
 	return opm
@@ -88,6 +90,14 @@ func Default() *OpMap {
 	opm.set("-", variable.Integer, variable.Integer, &minusInts{})
 	opm.set("*", variable.Integer, variable.Integer, &mulInts{})
 	opm.set("/", variable.Integer, variable.Integer, &divInts{})
+	opm.set("%", variable.Integer, variable.Integer, &modInts{})
+	opm.set("**", variable.Integer, variable.Integer, &powInts{})
+
+	opm.set("<<", variable.Integer, variable.Integer, &shiftLeftInts{})
+	opm.set(">>", variable.Integer, variable.Integer, &shiftRightInts{})
+	opm.set("&", variable.Integer, variable.Integer, &andInts{})
+	opm.set("|", variable.Integer, variable.Integer, &orInts{})
+	opm.set("^", variable.Integer, variable.Integer, &xorInts{})
 
 	opm.set("<", variable.Integer, variable.Integer, &lessThanInts{})
 	opm.set(">", variable.Integer, variable.Integer, &greaterThanInts{})
@@ -96,11 +106,35 @@ func Default() *OpMap {
 	opm.set("!=", variable.Integer, variable.Integer, &genNotEquals{})
 	opm.set("==", variable.Integer, variable.Integer, &genEquals{})
 
+	floatOps := map[string]Operator{
+		"+":  &plusFloats{},
+		"-":  &minusFloats{},
+		"*":  &mulFloats{},
+		"/":  &divFloats{},
+		"%":  &modFloats{},
+		"**": &powFloats{},
+		"<":  &lessThanFloats{},
+		">":  &greaterThanFloats{},
+		"<=": &lessThanEqualFloats{},
+		">=": &greaterThanEqualFloats{},
+		"!=": &genNotEquals{},
+		"==": &genEquals{},
+	}
+	for name, op := range floatOps {
+		opm.set(name, variable.Float, variable.Float, op)
+		opm.set(name, variable.Integer, variable.Float, &promoteToFloat{op: op})
+		opm.set(name, variable.Float, variable.Integer, &promoteToFloat{op: op})
+	}
+
 	opm.set("+", variable.String, variable.String, &plusStrings{})
 	opm.set("+", variable.Integer, variable.String, &coercedPlusString{})
 	opm.set("+", variable.String, variable.Integer, &stringPlusCoerced{})
+	opm.set("+", variable.Float, variable.String, &coercedPlusString{})
+	opm.set("+", variable.String, variable.Float, &stringPlusCoerced{})
 	opm.set("+", variable.Boolean, variable.String, &coercedPlusString{})
 	opm.set("+", variable.String, variable.Boolean, &stringPlusCoerced{})
+	opm.set("+", variable.Void, variable.String, &coercedPlusString{})
+	opm.set("+", variable.String, variable.Void, &stringPlusCoerced{})
 
 	opm.set("==", variable.Boolean, variable.Boolean, &equalsBools{})
 	opm.set("!=", variable.Boolean, variable.Boolean, &notEqualsBools{})
@@ -112,38 +146,114 @@ func Default() *OpMap {
 
 	opm.set("==", variable.Void, variable.Void, &equalsAlwaysTrue{})
 	opm.set("!=", variable.Void, variable.Void, &equalsAlwaysFalse{})
+	// nil is only equal to nil
+	for typ := variable.Integer; typ < variable.Void; typ++ {
+		opm.set("==", variable.Void, typ, &equalsAlwaysFalse{})
+		opm.set("==", typ, variable.Void, &equalsAlwaysFalse{})
+		opm.set("!=", variable.Void, typ, &equalsAlwaysTrue{})
+		opm.set("!=", typ, variable.Void, &equalsAlwaysTrue{})
+	}
 
 	return opm
 }
 
-var PrecedenceMap = map[string]int{
-	"*":  2,
-	"/":  2,
-	"+":  1,
-	"-":  1,
-	"&&": 0,
-	"||": 0,
-	"==": -1,
-	"!=": -1,
-	"<":  -1,
-	">":  -1,
-	"<=": -1,
-	">=": -1,
-}
-
-func LeftFirst(l, r string) bool {
-	pl, ok := PrecedenceMap[l]
-	if !ok {
-		panic(fmt.Sprintf("checked precedence on nonexistent op %s", l))
-	}
-	pr, ok := PrecedenceMap[r]
-	if !ok {
-		panic(fmt.Sprintf("checked precedence on nonexistent op %s", r))
-	}
+// operator precedence levels, from loosest to tightest binding
+const (
+	PrecAssign = iota + 1
+	PrecNullCoalesce
+	PrecLogicalOr
+	PrecLogicalAnd
+	PrecBitwiseOr
+	PrecBitwiseXor
+	PrecBitwiseAnd
+	PrecEquality
+	PrecComparison
+	PrecShift
+	PrecAdditive
+	PrecMultiplicative
+	// prefix operators bind tighter than every binary operator but **,
+	// so -2 ** 2 is -(2 ** 2) and -a * b is (-a) * b
+	PrecPrefix
+	PrecPow
+)
 
-	if pl == pr {
-		return true
-	}
+type Binding struct {
+	Prec int
+	// a right-associative operator groups a op b op c as a op (b op c)
+	RightAssoc bool
+}
+
+var bindings = map[string]Binding{
+	"=":  {PrecAssign, true},
+	"+=": {PrecAssign, true},
+	"-=": {PrecAssign, true},
+	"*=": {PrecAssign, true},
+	"/=": {PrecAssign, true},
+	"%=": {PrecAssign, true},
+	"??": {PrecNullCoalesce, false},
+	"||": {PrecLogicalOr, false},
+	"&&": {PrecLogicalAnd, false},
+	"|":  {PrecBitwiseOr, false},
+	"^":  {PrecBitwiseXor, false},
+	"&":  {PrecBitwiseAnd, false},
+	"==": {PrecEquality, false},
+	"!=": {PrecEquality, false},
+	"<":  {PrecComparison, false},
+	">":  {PrecComparison, false},
+	"<=": {PrecComparison, false},
+	">=": {PrecComparison, false},
+	"<<": {PrecShift, false},
+	">>": {PrecShift, false},
+	"+":  {PrecAdditive, false},
+	"-":  {PrecAdditive, false},
+	"*":  {PrecMultiplicative, false},
+	"/":  {PrecMultiplicative, false},
+	"%":  {PrecMultiplicative, false},
+	"**": {PrecPow, true},
+}
+
+var prefixOps = map[string]bool{
+	"-": true,
+	"!": true,
+	"~": true,
+}
+
+// the binary operator a compound assignment applies, a += b is a = a + b
+// and a++ is a = a + 1
+var compoundOps = map[string]string{
+	"+=": "+",
+	"-=": "-",
+	"*=": "*",
+	"/=": "/",
+	"%=": "%",
+	"++": "+",
+	"--": "-",
+}
+
+// returns the binary operator behind a compound assignment or increment operator
+func CompoundOf(op string) (string, bool) {
+	bin, ok := compoundOps[op]
+	return bin, ok
+}
+
+// returns how tightly the operator binds its operands when written between them
+func BindingOf(op string) (Binding, bool) {
+	b, ok := bindings[op]
+	return b, ok
+}
+
+// only binary operators can join two operands in a value expression,
+// assignments are statements and the rest (increment, unary-only operators) can't appear between operands
+func IsBinary(op string) bool {
+	b, ok := bindings[op]
+	return ok && b.Prec > PrecAssign
+}
+
+func IsAssignment(op string) bool {
+	b, ok := bindings[op]
+	return ok && b.Prec == PrecAssign
+}
 
-	return pl > pr
+func IsPrefix(op string) bool {
+	return prefixOps[op]
 }
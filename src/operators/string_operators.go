@@ -2,48 +2,27 @@ package operators
 
 import (
 	"gg-lang/src/variable"
-	"strconv"
 )
 
 // string + string
 type plusStrings struct{}
 
-func (p *plusStrings) Evaluate(left, right interface{}) interface{} {
-	return left.(string) + right.(string)
+func (p *plusStrings) Evaluate(left, right interface{}) (interface{}, error) {
+	return left.(string) + right.(string), nil
 }
 
 func (p *plusStrings) ResultType() variable.VarType {
 	return variable.String
 }
 
-type intPlusString struct{}
-
-func (*intPlusString) Evaluate(left, right interface{}) interface{} {
-	return strconv.Itoa(left.(int)) + right.(string)
-}
-
-func (*intPlusString) ResultType() variable.VarType {
-	return variable.String
-}
-
-type stringPlusInt struct{}
-
-func (*stringPlusInt) Evaluate(left, right interface{}) interface{} {
-	return left.(string) + strconv.Itoa(right.(int))
-}
-
-func (*stringPlusInt) ResultType() variable.VarType {
-	return variable.String
-}
-
 type coercedPlusString struct{}
 
-func (*coercedPlusString) Evaluate(left, right interface{}) interface{} {
+func (*coercedPlusString) Evaluate(left, right interface{}) (interface{}, error) {
 	lhs, err := variable.CoerceTo(left, variable.String)
 	if err != nil {
-		return nil
+		return nil, err
 	}
-	return lhs.(string) + right.(string)
+	return lhs.(string) + right.(string), nil
 }
 
 func (*coercedPlusString) ResultType() variable.VarType {
@@ -52,12 +31,12 @@ func (*coercedPlusString) ResultType() variable.VarType {
 
 type stringPlusCoerced struct{}
 
-func (*stringPlusCoerced) Evaluate(left, right interface{}) interface{} {
+func (*stringPlusCoerced) Evaluate(left, right interface{}) (interface{}, error) {
 	rhs, err := variable.CoerceTo(right, variable.String)
 	if err != nil {
-		return nil
+		return nil, err
 	}
-	return left.(string) + rhs.(string)
+	return left.(string) + rhs.(string), nil
 }
 func (*stringPlusCoerced) ResultType() variable.VarType {
 	return variable.String
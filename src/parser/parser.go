@@ -6,9 +6,20 @@ import (
 )
 
 type ItemPrinter[T any] func(in T) string
+
+// ItemSource returns the next item for a lazy Parser, and false once there are none left
+type ItemSource[T any] func() (T, bool)
+
 type Parser[T any] struct {
-	items []T
-	curr  int
+	// the items the parser holds. a lazy parser only holds a window around curr,
+	// items[0] is the item at index offset.
+	items  []T
+	offset int
+	curr   int
+
+	// set for lazy parsers, which pull items as they advance
+	source    ItemSource[T]
+	exhausted bool
 
 	stringer  ItemPrinter[T]
 	separator string
@@ -38,9 +49,13 @@ func (p *Parser[T]) IsDone() bool {
 
 // returns the range of items from TruncBefore inclusive to TruncAfter exclusive
 func (p *Parser[T]) truncate() []T {
-	lower := max(p.curr-p.TruncBefore, 0)
-	upper := min(p.curr+p.TruncAfter, len(p.items)-1)
-	return p.items[lower:upper]
+	p.load(p.curr + p.TruncAfter)
+	lower := max(p.curr-p.TruncBefore, p.offset)
+	upper := min(p.curr+p.TruncAfter, p.offset+len(p.items)-1)
+	if upper < lower {
+		return nil
+	}
+	return p.items[lower-p.offset : upper-p.offset]
 }
 
 func (p *Parser[T]) String() string {
@@ -55,18 +70,33 @@ func (p *Parser[T]) String() string {
 }
 
 func New[T any](items []T) *Parser[T] {
-	ret := &Parser[T]{
+	ret := newParser[T]()
+	ret.items = items
+	ret.exhausted = true
+
+	ret.Advance()
+	return ret
+}
+
+// NewLazy creates a parser that pulls items from source as it advances instead of
+// holding every item up front. Only the items String and Prev need are kept around.
+func NewLazy[T any](source ItemSource[T]) *Parser[T] {
+	ret := newParser[T]()
+	ret.source = source
+
+	ret.Advance()
+	return ret
+}
+
+func newParser[T any]() *Parser[T] {
+	return &Parser[T]{
 		curr:         -1,
-		items:        items,
 		WrapSelected: true,
 		TruncBefore:  5,
 		TruncAfter:   2,
 		stringer:     func(in T) string { return fmt.Sprintf("%v", in) },
 		separator:    ",",
 	}
-
-	ret.Advance()
-	return ret
 }
 
 func (p *Parser[T]) Index() int {
@@ -78,19 +108,56 @@ func (p *Parser[T]) Advance() {
 	p.assignPublicProps()
 }
 
+// pulls items from the source until the item at index i is held, or the source runs out
+func (p *Parser[T]) load(i int) {
+	for !p.exhausted && i >= p.offset+len(p.items) {
+		item, ok := p.source()
+		if !ok {
+			p.exhausted = true
+			return
+		}
+		p.items = append(p.items, item)
+	}
+}
+
+func (p *Parser[T]) at(i int) (T, bool) {
+	p.load(i)
+	if i >= p.offset && i < p.offset+len(p.items) {
+		return p.items[i-p.offset], true
+	}
+	var ret T
+	return ret, false
+}
+
 func (p *Parser[T]) assignPublicProps() {
-	p.HasCurr = p.curr < len(p.items) && p.curr >= 0
-	p.HasNext = p.curr+1 < len(p.items) && p.curr+1 >= 0
-	if p.HasCurr {
-		p.Curr = p.items[p.curr]
+	p.Curr, p.HasCurr = p.at(p.curr)
+	p.Next, p.HasNext = p.at(p.curr + 1)
+
+	// a lazy parser forgets the items it can no longer need
+	if p.source != nil {
+		if drop := p.curr - max(p.TruncBefore, 1) - p.offset; drop > 0 && drop <= len(p.items) {
+			p.items = p.items[drop:]
+			p.offset += drop
+		}
 	}
-	if p.HasNext {
-		p.Next = p.items[p.curr+1]
+}
+
+// returns the item before Curr, which is the last item that was advanced past
+func (p *Parser[T]) Prev() (T, bool) {
+	if p.curr > 0 {
+		return p.at(p.curr - 1)
 	}
+	var ret T
+	return ret, false
+}
+
+// returns the item n items after Curr without advancing, Peek(0) is Curr
+func (p *Parser[T]) Peek(n int) (T, bool) {
+	return p.at(p.curr + n)
 }
 
 func (p *Parser[T]) Back() {
-	if p.curr > 0 {
+	if _, ok := p.Prev(); ok {
 		p.curr--
 		p.assignPublicProps()
 	}
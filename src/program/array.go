@@ -9,12 +9,12 @@ import (
 type Array = []variable.RuntimeValue
 
 func (p *Program) evaluateArrayDeclExpression(expr *gg_ast.ArrayDeclExpression) (*variable.RuntimeValue, error) {
-	val := make(Array, len(expr.Elements))
-	for i, elem := range expr.Elements {
-		v, err := p.evaluateValueExpr(elem)
-		if err != nil {
-			return nil, err
-		}
+	elems, _, err := p.evaluateList(expr.Elements)
+	if err != nil {
+		return nil, err
+	}
+	val := make(Array, len(elems))
+	for i, v := range elems {
 		val[i] = *v
 	}
 
@@ -24,68 +24,67 @@ func (p *Program) evaluateArrayDeclExpression(expr *gg_ast.ArrayDeclExpression)
 	}, nil
 }
 
-func (p *Program) evaluateArrayIndexExpression(expr *gg_ast.ArrayIndexExpression) (*variable.RuntimeValue, error) {
+// evaluates the index of an index expression on arr, the already evaluated container.
+// the index is checked to be in range.
+func (p *Program) evaluateArrayIndex(expr *gg_ast.ArrayIndexExpression, arr *variable.RuntimeValue) (Array, int64, error) {
+	arrVal, ok := arr.Val.(Array)
+	if !ok {
+		return nil, 0, gg.Runtime("%s is not an array or an object, evaluating %s", expr.Array.Name(), expr.Name())
+	}
+
 	index, err := p.evaluateValueExpr(expr.Index)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	arr := p.currentScope().findVariable(expr.Array.Name())
-	arrVal, ok := arr.RuntimeValue.Val.(Array)
+	indexVal, ok := index.Val.(int64)
 	if !ok {
-		return nil, gg.Runtime("array index expression must reference an array\n%+v", expr)
+		return nil, 0, gg.Runtime("array index must evaluate to int, evaluating %s", expr.Name())
 	}
 
-	var indexVal int
-	if val, ok := index.Val.(int); !ok {
-		return nil, gg.Runtime("array index must evaluate to int\n%+v", expr)
-	} else {
-		indexVal = val
-	}
-
-	length := len(arrVal)
-	if indexVal < 0 || indexVal >= length {
-		return nil, gg.Runtime("array index out of range\n%+v", expr)
+	if indexVal < 0 || indexVal >= int64(len(arrVal)) {
+		// left to the caller, a?.[i] is nil when i is out of range
+		if expr.Optional {
+			return arrVal, indexVal, nil
+		}
+		return nil, 0, gg.Runtime("array index %d out of range for length %d, evaluating %s", indexVal, len(arrVal), expr.Name())
 	}
-
-	return &variable.RuntimeValue{
-		Val: arrVal[indexVal].Val,
-		Typ: arrVal[indexVal].Typ,
-	}, nil
+	return arrVal, indexVal, nil
 }
 
-func (p *Program) evaluateArrayIndexAssignmentExpression(expr *gg_ast.ArrayIndexAssignmentExpression) error {
-	arr := p.currentScope().findVariable(expr.Array.Name())
-	if arr == nil {
-		return gg.Runtime("undefined variable: %s\n%+v", expr.Array.Name(), expr)
-	}
-
-	// Check if array is actually an array
-	arrVal, ok := arr.RuntimeValue.Val.(Array)
-	if !ok {
-		return gg.Runtime("array index assignment expression must reference an array\n%+v", expr)
-	}
-
-	// Check if index is an integer within bounds
-	indexVal, err := p.evaluateValueExpr(expr.Index)
+// arr[i] on arrays, obj[key] on objects
+func (p *Program) evaluateArrayIndexExpression(expr *gg_ast.ArrayIndexExpression) (*variable.RuntimeValue, error) {
+	container, err := p.evaluateValueExpr(expr.Array)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	index, ok := indexVal.Val.(int)
-	if !ok {
-		return gg.Runtime("array index must evaluate to int\n%+v", expr)
+	if expr.Optional && container.Typ == variable.Void {
+		return &variable.RuntimeValue{Typ: variable.Void}, nil
 	}
-
-	if index < 0 || index >= len(arrVal) {
-		return gg.Runtime("array index out of range\n%+v", expr)
+	if obj, ok := container.Val.(Object); ok {
+		key, err := p.evaluateKey(expr.Index)
+		if err != nil {
+			return nil, err
+		}
+		property, exists := obj.Get(key)
+		if !exists {
+			if expr.Optional {
+				return &variable.RuntimeValue{Typ: variable.Void}, nil
+			}
+			return nil, gg.Runtime("undefined property: %s in object %s, evaluating %s", key, expr.Array.Name(), expr.Name())
+		}
+		return property, nil
 	}
 
-	// evaluate right side of the assignment expression
-	newVal, err := p.evaluateValueExpr(expr.Value)
+	arrVal, index, err := p.evaluateArrayIndex(expr, container)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if index < 0 || index >= int64(len(arrVal)) {
+		return &variable.RuntimeValue{Typ: variable.Void}, nil
 	}
 
-	arrVal[index] = *newVal
-	return nil
+	return &variable.RuntimeValue{
+		Val: arrVal[index].Val,
+		Typ: arrVal[index].Typ,
+	}, nil
 }
@@ -0,0 +1,164 @@
+package program
+
+import (
+	"gg-lang/src/gg"
+	"gg-lang/src/gg_ast"
+	"gg-lang/src/operators"
+	"gg-lang/src/variable"
+)
+
+// a place a value can be stored in, like a variable, a property or an array element.
+// the container and key are evaluated once, when the reference is resolved.
+type reference struct {
+	get func() (*variable.RuntimeValue, error)
+	set func(val *variable.RuntimeValue) error
+}
+
+func (p *Program) resolveReference(target gg_ast.ValueExpression) (*reference, error) {
+	switch target := target.(type) {
+	case *gg_ast.Identifier:
+		if target.Kind() != gg_ast.ExprVariable {
+			break
+		}
+		name := target.Name()
+		return &reference{
+			get: func() (*variable.RuntimeValue, error) {
+				v := p.findVariable(name)
+				if v == nil {
+					return nil, gg.Runtime("undefined variable: %s", name)
+				}
+				return v.RuntimeValue, nil
+			},
+			set: func(val *variable.RuntimeValue) error {
+				return p.setVariable(name, val)
+			},
+		}, nil
+	case *gg_ast.DotAccessExpression:
+		obj, err := p.evaluateObject(target)
+		if err != nil {
+			return nil, err
+		}
+		return &reference{
+			get: func() (*variable.RuntimeValue, error) {
+				property, exists := obj.Get(target.Property)
+				if !exists {
+					return nil, gg.Runtime("undefined property: %s in object %s, evaluating %s", target.Property, target.Object.Name(), target.Name())
+				}
+				return property, nil
+			},
+			set: func(val *variable.RuntimeValue) error {
+				obj.Set(target.Property, val)
+				return nil
+			},
+		}, nil
+	case *gg_ast.ArrayIndexExpression:
+		container, err := p.evaluateValueExpr(target.Array)
+		if err != nil {
+			return nil, err
+		}
+		if obj, ok := container.Val.(Object); ok {
+			key, err := p.evaluateKey(target.Index)
+			if err != nil {
+				return nil, err
+			}
+			return &reference{
+				get: func() (*variable.RuntimeValue, error) {
+					property, exists := obj.Get(key)
+					if !exists {
+						return nil, gg.Runtime("undefined property: %s in object %s, evaluating %s", key, target.Array.Name(), target.Name())
+					}
+					return property, nil
+				},
+				set: func(val *variable.RuntimeValue) error {
+					obj.Set(key, val)
+					return nil
+				},
+			}, nil
+		}
+
+		arr, index, err := p.evaluateArrayIndex(target, container)
+		if err != nil {
+			return nil, err
+		}
+		return &reference{
+			get: func() (*variable.RuntimeValue, error) {
+				return &variable.RuntimeValue{Val: arr[index].Val, Typ: arr[index].Typ}, nil
+			},
+			set: func(val *variable.RuntimeValue) error {
+				arr[index] = *val
+				return nil
+			},
+		}, nil
+	}
+
+	return nil, gg.Runtime("cannot assign to %s, only variables, properties and array elements can be assigned to", target.Name())
+}
+
+// sets the variable called name. in compat mode a variable that doesn't exist yet
+// is declared in the current scope, otherwise it has to be declared with let first.
+func (p *Program) setVariable(name string, val *variable.RuntimeValue) error {
+	existing := p.findVariable(name)
+	if existing != nil {
+		if existing.Const {
+			return gg.Runtime("cannot assign to constant %s", name)
+		}
+		existing.RuntimeValue = val // garbage collect old value
+		return nil
+	}
+	if !p.Compat {
+		return gg.Runtime("assignment to undeclared variable %s, declare it with let first", name)
+	}
+	_, err := p.currentScope().softDeclareVar(name, val)
+	return err
+}
+
+// declares the names of the pattern in the current scope
+func (p *Program) execDeclaration(decl *gg_ast.DeclarationStatement) error {
+	// let x; declares x as void
+	val, path := &variable.RuntimeValue{Typ: variable.Void}, ""
+	if decl.Value != nil {
+		var err error
+		val, err = p.evaluateValueExpr(decl.Value)
+		if err != nil {
+			return err
+		}
+		path = decl.Value.Name()
+	}
+	return p.destructure(decl.Pattern, val, path, func(name string, val *variable.RuntimeValue) error {
+		v, err := p.currentScope().declareVar(name, val)
+		if err != nil {
+			return err
+		}
+		v.Const = decl.Const
+		return nil
+	})
+}
+
+func (p *Program) evaluateAssignment(expr *gg_ast.AssignmentExpression) error {
+	ref, err := p.resolveReference(expr.Target)
+	if err != nil {
+		return err
+	}
+
+	// ++ and -- add or subtract 1
+	val := &variable.RuntimeValue{Val: int64(1), Typ: variable.Integer}
+	if expr.Value != nil {
+		val, err = p.evaluateValueExpr(expr.Value)
+		if err != nil {
+			return err
+		}
+	}
+
+	// a compound assignment applies its operator to the current value, a += b is a = a + b
+	if binOp, ok := operators.CompoundOf(expr.Op.Symbol); ok {
+		current, err := ref.get()
+		if err != nil {
+			return err
+		}
+		val, err = p.applyOperator(binOp, current, val, expr)
+		if err != nil {
+			return err
+		}
+	}
+	return ref.set(val)
+}
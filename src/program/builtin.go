@@ -17,7 +17,7 @@ func (p *Print) Name() string {
 }
 func (p *Print) Call(args ...*variable.RuntimeValue) (*variable.RuntimeValue, error) {
 	for _, arg := range args {
-		fmt.Println(arg.Val)
+		fmt.Println(variable.ToString(arg.Val))
 	}
 	return &variable.RuntimeValue{
 		Val: nil,
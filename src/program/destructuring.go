@@ -0,0 +1,86 @@
+package program
+
+import (
+	"fmt"
+	"gg-lang/src/gg"
+	"gg-lang/src/gg_ast"
+	"gg-lang/src/variable"
+)
+
+// binds the names in pat to the parts of val it describes. path is how val was reached, like
+// user.address, so an error can say exactly which part of val didn't fit the pattern.
+// a nil val is a missing value, which only fits a pattern with a default.
+func (p *Program) destructure(pat gg_ast.Pattern, val *variable.RuntimeValue, path string, bind func(name string, val *variable.RuntimeValue) error) error {
+	if def, ok := pat.(*gg_ast.DefaultPattern); ok {
+		if val == nil {
+			defVal, err := p.evaluateValueExpr(def.Default)
+			if err != nil {
+				return err
+			}
+			val = defVal
+		}
+		pat = def.Pattern
+	}
+	if val == nil {
+		return gg.Runtime("%s is undefined", path)
+	}
+
+	switch pat := pat.(type) {
+	case *gg_ast.WildcardPattern:
+		return nil
+	case *gg_ast.BindingPattern:
+		return bind(pat.Name, val)
+	case *gg_ast.TargetPattern:
+		// stored like in a plain assignment, the parser only allows targets in destructuring assignments
+		ref, err := p.resolveReference(pat.Target)
+		if err != nil {
+			return err
+		}
+		return ref.set(val)
+	case *gg_ast.ArrayPattern:
+		arr, ok := val.Val.(Array)
+		if !ok {
+			return gg.Runtime("%s is not an array", path)
+		}
+		for i, elem := range pat.Elements {
+			var elemVal *variable.RuntimeValue
+			if i < len(arr) {
+				elemVal = &variable.RuntimeValue{Val: arr[i].Val, Typ: arr[i].Typ}
+			}
+			if err := p.destructure(elem, elemVal, fmt.Sprintf("%s[%d]", path, i), bind); err != nil {
+				return err
+			}
+		}
+		if pat.Rest == "" {
+			return nil
+		}
+		rest := Array{}
+		if len(arr) > len(pat.Elements) {
+			rest = append(rest, arr[len(pat.Elements):]...)
+		}
+		return bind(pat.Rest, &variable.RuntimeValue{Val: rest, Typ: variable.Array})
+	case *gg_ast.ObjectPattern:
+		obj, ok := val.Val.(Object)
+		if !ok {
+			return gg.Runtime("%s is not an object", path)
+		}
+		for _, prop := range pat.Properties {
+			propVal, _ := obj.Get(prop.Key)
+			if err := p.destructure(prop.Pattern, propVal, path+"."+prop.Key, bind); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return gg.Crit("invalid destructuring pattern: %T", pat)
+}
+
+func (p *Program) evaluateDestructuringAssignment(expr *gg_ast.DestructuringAssignment) error {
+	val, err := p.evaluateValueExpr(expr.Value)
+	if err != nil {
+		return err
+	}
+	return p.destructure(expr.Pattern, val, expr.Value.Name(), func(name string, val *variable.RuntimeValue) error {
+		return p.setVariable(name, val)
+	})
+}
@@ -0,0 +1,19 @@
+package program
+
+import (
+	"errors"
+	"gg-lang/src/gg"
+	"gg-lang/src/gg_ast"
+)
+
+// runtime errors are usually raised without knowing where in the source they
+// happened. the innermost expression that sees an error without a position
+// gives it its own, so the error points at the expression that actually failed.
+func withExprPos(err error, expr gg_ast.Expression) error {
+	var rtErr *gg.RuntimeErr
+	if err == nil || !errors.As(err, &rtErr) || rtErr.Pos.IsValid() {
+		return err
+	}
+	rtErr.Pos = expr.Pos()
+	return err
+}
@@ -1,22 +1,20 @@
 package program
 
 import (
+	"fmt"
 	"gg-lang/src/gg"
 	"gg-lang/src/gg_ast"
 	"gg-lang/src/variable"
 )
 
-func (p *Program) RunExpression(expr gg_ast.Expression) error {
-	// dont execute anything if there's a return value right now
-	if p.returnValue != nil {
+func (p *Program) RunExpression(expr gg_ast.Expression) (err error) {
+	defer func() { err = withExprPos(err, expr) }()
+
+	// dont execute anything if there's a return value or a break or continue right now
+	if p.returnValue != nil || p.loopSignal != nil {
 		return nil
 	}
 	switch expr.(type) {
-	case *gg_ast.ArrayIndexAssignmentExpression:
-		expr := expr.(*gg_ast.ArrayIndexAssignmentExpression)
-		if err := p.evaluateArrayIndexAssignmentExpression(expr); err != nil {
-			return err
-		}
 	case *gg_ast.TryCatchExpression:
 		expr := expr.(*gg_ast.TryCatchExpression)
 		if err := p.evaluateTryCatchExpression(expr); err != nil {
@@ -28,6 +26,10 @@ func (p *Program) RunExpression(expr gg_ast.Expression) error {
 			return err
 		}
 		p.returnValue = val
+	case *gg_ast.BreakStatement:
+		p.loopSignal = &loopSignal{isBreak: true, label: expr.(*gg_ast.BreakStatement).Label}
+	case *gg_ast.ContinueStatement:
+		p.loopSignal = &loopSignal{label: expr.(*gg_ast.ContinueStatement).Label}
 	case gg_ast.BlockStatement:
 		block := expr.(gg_ast.BlockStatement)
 		err := p.runBlockStmtNewScope(block)
@@ -38,8 +40,12 @@ func (p *Program) RunExpression(expr gg_ast.Expression) error {
 		if err := p.evaluateAssignment(expr.(*gg_ast.AssignmentExpression)); err != nil {
 			return err
 		}
-	case *gg_ast.DotAccessAssignmentExpression:
-		if err := p.evaluateDotAccessAssignment(expr.(*gg_ast.DotAccessAssignmentExpression)); err != nil {
+	case *gg_ast.DestructuringAssignment:
+		if err := p.evaluateDestructuringAssignment(expr.(*gg_ast.DestructuringAssignment)); err != nil {
+			return err
+		}
+	case *gg_ast.DeclarationStatement:
+		if err := p.execDeclaration(expr.(*gg_ast.DeclarationStatement)); err != nil {
 			return err
 		}
 	case *gg_ast.FunctionDeclExpression:
@@ -56,10 +62,16 @@ func (p *Program) RunExpression(expr gg_ast.Expression) error {
 		if done {
 			return err
 		}
+	case *gg_ast.ForInExpression:
+		if err := p.execForInExpression(expr.(*gg_ast.ForInExpression)); err != nil {
+			return err
+		}
 	case *gg_ast.IfElseStatement:
-		ifElse := expr.(*gg_ast.IfElseStatement)
-		err := p.execIfElse(ifElse)
-		if err != nil {
+		if _, err := p.evaluateIfElse(expr.(*gg_ast.IfElseStatement)); err != nil {
+			return err
+		}
+	case *gg_ast.MatchExpression:
+		if _, err := p.evaluateMatchExpression(expr.(*gg_ast.MatchExpression)); err != nil {
 			return err
 		}
 	case *gg_ast.FunctionCallExpression:
@@ -68,6 +80,11 @@ func (p *Program) RunExpression(expr gg_ast.Expression) error {
 		if err != nil {
 			return err
 		}
+	case gg_ast.ValueExpression:
+		// the value of the last expression of a block that isn't used as a value
+		if _, err := p.evaluateValueExpr(expr.(gg_ast.ValueExpression)); err != nil {
+			return err
+		}
 	default:
 		return gg.Crit("Invalid top-level expression: %s\n%s", expr.Kind().String(), gg_ast.NoBuilderExprString(expr))
 	}
@@ -76,70 +93,168 @@ func (p *Program) RunExpression(expr gg_ast.Expression) error {
 
 func (p *Program) execForLoopExpression(expr *gg_ast.ForLoopExpression) (error, bool) {
 	loop := expr
+	// variables made by the init statement only live as long as the loop
+	p.enterNewScope()
+	defer p.exitScope()
+	if loop.Init != nil {
+		if err := p.RunExpression(loop.Init); err != nil {
+			return err, true
+		}
+	}
+	// like in JS every iteration gets its own copy of the init variables,
+	// so a closure made in the body keeps the value of its iteration
+	p.copyCurrentScope()
+
 	for {
-		val, err := p.evaluateValueExpr(loop.Condition)
+		if loop.Condition != nil {
+			val, err := p.evaluateValueExpr(loop.Condition)
+			if err != nil {
+				return err, true
+			}
+			if _, ok := val.Val.(bool); !ok {
+				return gg.Runtime("loop condition must evaluate to bool\n%+v", expr), true
+			}
+			if !val.Val.(bool) {
+				break
+			}
+		}
+		err := p.runBlockStmtNewScope(loop.Body)
 		if err != nil {
 			return err, true
 		}
-		if _, ok := val.Val.(bool); !ok {
-			return gg.Runtime("loop condition must evaluate to bool\n%+v", expr), true
-		}
-		if !val.Val.(bool) {
+
+		if p.loopDone(loop.Label) {
 			break
 		}
-		err = p.runBlockStmtNewScope(loop.Body)
-		if err != nil {
-			return err, true
+		p.copyCurrentScope()
+		if loop.Post != nil {
+			if err := p.RunExpression(loop.Post); err != nil {
+				return err, true
+			}
 		}
 	}
 	return nil, false
 }
 
-func (p *Program) execIfElse(expr gg_ast.Expression) error {
-	ifElse := expr.(*gg_ast.IfElseStatement)
-	cond, err := p.evaluateValueExpr(ifElse.Condition)
+func (p *Program) execForInExpression(loop *gg_ast.ForInExpression) error {
+	iterable, err := p.evaluateValueExpr(loop.Iterable)
 	if err != nil {
 		return err
 	}
-	if _, ok := cond.Val.(bool); !ok {
-		return gg.Runtime("if condition must evaluate to bool\n%+v", expr)
+
+	// what every iteration binds, collected up front so changes to the iterable don't affect the loop
+	var keys, values []*variable.RuntimeValue
+	switch iterable.Typ {
+	case variable.Array:
+		for i, elem := range iterable.Val.(Array) {
+			keys = append(keys, &variable.RuntimeValue{Val: int64(i), Typ: variable.Integer})
+			values = append(values, &variable.RuntimeValue{Val: elem.Val, Typ: elem.Typ})
+		}
+	case variable.String:
+		i := int64(0)
+		for _, r := range iterable.Val.(string) {
+			keys = append(keys, &variable.RuntimeValue{Val: i, Typ: variable.Integer})
+			values = append(values, &variable.RuntimeValue{Val: string(r), Typ: variable.String})
+			i++
+		}
+	case variable.Object:
+		obj := iterable.Val.(Object)
+		for _, name := range obj.Keys() {
+			val, _ := obj.Get(name)
+			keys = append(keys, &variable.RuntimeValue{Val: name, Typ: variable.String})
+			values = append(values, val)
+		}
+		// for k in obj iterates the keys
+		if loop.Key == "" {
+			values = keys
+		}
+	default:
+		return gg.Runtime("cannot iterate over %s, for-in loops take an array, a string or an object", iterable.Typ.String())
 	}
-	if cond.Val.(bool) {
-		err = p.runBlockStmtNewScope(ifElse.Body)
-		if err != nil {
+
+	for i := range values {
+		if err := p.runForInIteration(loop, keys[i], values[i]); err != nil {
 			return err
 		}
-	} else {
-		if ifElse.ElseExpression != nil {
-			err = p.RunExpression(ifElse.ElseExpression)
-			if err != nil {
-				return err
-			}
+		if p.loopDone(loop.Label) {
+			break
 		}
 	}
 	return nil
 }
 
-func (p *Program) evaluateAssignment(expr *gg_ast.AssignmentExpression) error {
-	if expr.Target.Kind() != gg_ast.ExprVariable {
-		return gg.Runtime("invalid assignment target: %s", expr.Target.Tok.Symbol)
-	}
+// every iteration gets its own scope for the loop variables, so routines
+// declared in the body capture the values of that iteration
+func (p *Program) runForInIteration(loop *gg_ast.ForInExpression, key, value *variable.RuntimeValue) error {
+	p.enterNewScope()
+	defer p.exitScope()
 
-	if expr.Value.Kind() > gg_ast.SentinelValueExpression {
-		return gg.Runtime("cannot make value for %v", expr)
+	if loop.Key != "" {
+		if _, err := p.currentScope().declareVar(loop.Key, key); err != nil {
+			return err
+		}
+	}
+	if loop.ValuePattern == nil {
+		if _, err := p.currentScope().declareVar(loop.Value, value); err != nil {
+			return err
+		}
+		return p.runBlockStmt(loop.Body)
 	}
 
-	val, err := p.evaluateValueExpr(expr.Value)
+	// the path of an element is arr[i] or obj.key
+	path := loop.Iterable.Name() + "." + variable.ToString(key.Val)
+	if key.Typ == variable.Integer {
+		path = fmt.Sprintf("%s[%d]", loop.Iterable.Name(), key.Val)
+	}
+	err := p.destructure(loop.ValuePattern, value, path, func(name string, val *variable.RuntimeValue) error {
+		_, err := p.currentScope().declareVar(name, val)
+		return err
+	})
 	if err != nil {
 		return err
 	}
+	return p.runBlockStmt(loop.Body)
+}
 
-	existing := p.findVariable(expr.Target.Name())
-	if existing != nil {
-		existing.RuntimeValue = val // garbage collect old value
-		return nil
+// called after every iteration of a loop, handles a break or continue and
+// returns true if the loop has to stop
+func (p *Program) loopDone(label string) bool {
+	if p.returnValue != nil {
+		return true
+	}
+
+	signal := p.loopSignal
+	if signal == nil {
+		return false
+	}
+	// a labeled signal for an outer loop is left for that loop to handle
+	if signal.label != "" && signal.label != label {
+		return true
+	}
+	p.loopSignal = nil
+	return signal.isBreak
+}
+
+// evaluates the branch the condition picks, the value of an if without an else is void when the condition is false
+func (p *Program) evaluateIfElse(ifElse *gg_ast.IfElseStatement) (*variable.RuntimeValue, error) {
+	cond, err := p.evaluateValueExpr(ifElse.Condition)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := cond.Val.(bool); !ok {
+		return nil, gg.Runtime("if condition must evaluate to bool\n%+v", ifElse)
+	}
+	if cond.Val.(bool) {
+		return p.evaluateBlockValue(ifElse.Body)
 	}
 
-	_, err = p.currentScope().softDeclareVar(expr.Target.Tok.Symbol, val)
-	return err
+	switch alt := ifElse.ElseExpression.(type) {
+	case nil:
+		return &variable.RuntimeValue{Typ: variable.Void}, nil
+	case gg_ast.BlockStatement:
+		return p.evaluateBlockValue(alt)
+	case *gg_ast.IfElseStatement:
+		return p.evaluateIfElse(alt)
+	}
+	return nil, gg.Crit("invalid else expression: %T", ifElse.ElseExpression)
 }
@@ -1,68 +1,65 @@
 package program
 
 import (
+	"fmt"
 	"gg-lang/src/gg"
 	"gg-lang/src/gg_ast"
 	"gg-lang/src/variable"
+	"slices"
 )
 
 func (p *Program) call(f *gg_ast.FunctionCallExpression) (*variable.RuntimeValue, error) {
 	// find the function
-	v := p.currentScope().findVariable(f.Name())
-	if v == nil {
-		return nil, gg.Runtime("undefined function %s, evaluating\n%s", f.Id.Tok.Symbol, gg_ast.NoBuilderExprString(f))
+	v, err := p.evaluateCallee(f)
+	if err != nil {
+		return nil, err
+	}
+	// f?.() doesn't evaluate its arguments when f is nil
+	if f.Optional && v.Typ == variable.Void {
+		return &variable.RuntimeValue{Typ: variable.Void}, nil
 	}
 
 	// check if callable
-	if _, ok := v.RuntimeValue.Val.(Func); !ok {
-		if _, ok := v.RuntimeValue.Val.(*RuntimeFunc); !ok {
-			return nil, gg.Runtime("%s is not callable, evaluating\n%s", f.Id.Tok.Symbol, gg_ast.NoBuilderExprString(f))
+	if _, ok := v.Val.(Func); !ok {
+		if _, ok := v.Val.(*RuntimeFunc); !ok {
+			return nil, gg.Runtime("%s is not callable, evaluating\n%s", f.Name(), gg_ast.NoBuilderExprString(f))
 		}
 	}
 
 	// build values for arguments
-	vals := make([]*variable.RuntimeValue, len(f.Args))
-	for i, arg := range f.Args {
-		value, err := p.evaluateValueExpr(arg)
+	vals, sources, err := p.evaluateList(f.Args)
+	if err != nil {
+		return nil, err
+	}
+	named := make([]*variable.RuntimeValue, len(f.NamedArgs))
+	for i, arg := range f.NamedArgs {
+		value, err := p.evaluateValueExpr(arg.Value)
 		if err != nil {
 			return nil, err
 		}
 
-		vals[i] = value
+		named[i] = value
 	}
 
 	// run builtin
-	if bn, ok := v.RuntimeValue.Val.(Func); ok {
+	if bn, ok := v.Val.(Func); ok {
+		if len(named) > 0 {
+			return nil, gg.Runtime("%s doesn't take named arguments, evaluating\n%s", f.Name(), gg_ast.NoBuilderExprString(f))
+		}
 		return p.builtinFuncCall(bn, vals)
 	}
 
 	// set up func expression
-	runtimeFunc := v.RuntimeValue.Val.(*RuntimeFunc)
-	if len(runtimeFunc.Decl.Params) != len(f.Args) {
-		return nil, gg.Runtime("param count mismatch on %s, evaluating\n%s", f.Id.Tok.Symbol, gg_ast.NoBuilderExprString(f))
-	}
-
-	// build variables for new scope
-	var scopedVariables []variable.Variable
-	for i := range f.Args {
-		value := vals[i]
+	runtimeFunc := v.Val.(*RuntimeFunc)
 
-		paramTok := runtimeFunc.Decl.Params[i]
-		scopedVariables = append(scopedVariables, variable.Variable{
-			Name:         paramTok.Symbol,
-			RuntimeValue: value,
-		})
-	}
-	// enter the captured scope
+	// enter the captured scope, and then a new one for the arguments
 	p.enterCapturedScope(runtimeFunc.CapturedScope)
 	p.enterNewScope()
 	defer p.exitScope()
 	defer p.exitScope()
 
-	// and then a new one to temporarily save the arguments
-	for _, v := range scopedVariables {
-		temp := v
-		p.currentScope().variables[temp.Name] = &temp
+	if err := p.bindArguments(runtimeFunc, f, vals, sources, named); err != nil {
+		return nil, err
 	}
 
 	// run the function body
@@ -82,6 +79,101 @@ func (p *Program) call(f *gg_ast.FunctionCallExpression) (*variable.RuntimeValue
 	}, nil
 }
 
+// declares the params of fn in the current scope. params the call leaves out get their default,
+// which is evaluated once the params before it are declared, so it can refer to them.
+// sources are where the positional arguments came from, see evaluateList.
+func (p *Program) bindArguments(fn *RuntimeFunc, f *gg_ast.FunctionCallExpression, positional []*variable.RuntimeValue, sources []string, named []*variable.RuntimeValue) error {
+	params := fn.Decl.Params
+	args := make([]*variable.RuntimeValue, len(params))
+
+	// positional arguments that don't fit the other params go in the rest param
+	fixed := len(params)
+	hasRest := fixed > 0 && params[fixed-1].IsRest
+	if hasRest {
+		fixed--
+	}
+	rest := Array{}
+	for i, val := range positional {
+		if i < fixed {
+			args[i] = val
+			continue
+		}
+		if !hasRest {
+			return gg.Runtime("too many arguments in call to %s, it takes %d but got %d", f.Name(), fixed, len(positional))
+		}
+		rest = append(rest, *val)
+	}
+
+	for i, arg := range f.NamedArgs {
+		name := arg.Name.Symbol
+		idx := slices.IndexFunc(params, func(param gg_ast.Param) bool { return param.Name.Symbol == name })
+		switch {
+		case idx < 0:
+			return gg.Runtime("unknown argument %s in call to %s", name, f.Name())
+		case params[idx].IsRest:
+			return gg.Runtime("rest parameter %s can't be passed by name in call to %s", name, f.Name())
+		case args[idx] != nil:
+			return gg.Runtime("argument %s is given more than once in call to %s", name, f.Name())
+		}
+		args[idx] = named[i]
+	}
+
+	for i, param := range params {
+		// destructured params are known by their position
+		name := param.Name.Symbol
+		if param.Pattern != nil {
+			name = fmt.Sprintf("%d", i+1)
+		}
+		// and a destructured argument by the variable it came from, if it came from one
+		path := "argument " + name
+		if i < len(sources) && sources[i] != "" {
+			path = sources[i]
+		}
+
+		val := args[i]
+		switch {
+		case param.IsRest:
+			val = &variable.RuntimeValue{Val: rest, Typ: variable.Array}
+		case val == nil && param.Default != nil:
+			def, err := p.evaluateValueExpr(param.Default)
+			if err != nil {
+				return err
+			}
+			val = def
+		case val == nil:
+			return gg.Runtime("missing argument %s in call to %s", name, f.Name())
+		}
+
+		if param.Pattern != nil {
+			err := p.destructure(param.Pattern, val, path, func(name string, val *variable.RuntimeValue) error {
+				_, err := p.currentScope().declareVar(name, val)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		p.currentScope().variables[name] = &variable.Variable{
+			Name:         name,
+			RuntimeValue: val,
+		}
+	}
+	return nil
+}
+
+// the callee can be any value expression, like obj.handler or makeHandler()
+func (p *Program) evaluateCallee(f *gg_ast.FunctionCallExpression) (*variable.RuntimeValue, error) {
+	if id, ok := f.Callee.(*gg_ast.Identifier); ok && id.Kind() == gg_ast.ExprVariable {
+		v := p.findVariable(id.Name())
+		if v == nil {
+			return nil, gg.Runtime("undefined function %s, evaluating\n%s", id.Name(), gg_ast.NoBuilderExprString(f))
+		}
+		return v.RuntimeValue, nil
+	}
+	return p.evaluateValueExpr(f.Callee)
+}
+
 func (p *Program) builtinFuncCall(f Func, args []*variable.RuntimeValue) (*variable.RuntimeValue, error) {
 	res, err := f.Call(args...)
 	if err != nil {
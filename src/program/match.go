@@ -0,0 +1,150 @@
+package program
+
+import (
+	"gg-lang/src/gg"
+	"gg-lang/src/gg_ast"
+	"gg-lang/src/operators"
+	"gg-lang/src/variable"
+	"maps"
+)
+
+// runs the first arm whose pattern matches the subject and whose guard holds.
+// an arm with a block body evaluates to the value of the block.
+func (p *Program) evaluateMatchExpression(expr *gg_ast.MatchExpression) (*variable.RuntimeValue, error) {
+	subject, err := p.evaluateValueExpr(expr.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, arm := range expr.Arms {
+		bindings := make(map[string]*variable.RuntimeValue)
+		matched, err := p.matchPattern(arm.Pattern, subject, bindings)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+
+		res, matched, err := p.runMatchArm(arm, bindings)
+		if err != nil || matched {
+			return res, err
+		}
+	}
+
+	return nil, gg.Runtime("no match arm matched %s", variable.ToString(subject.Val))
+}
+
+// the bindings of an arm live in their own scope, for the guard and the body.
+// returns false if the guard doesn't hold.
+func (p *Program) runMatchArm(arm *gg_ast.MatchArm, bindings map[string]*variable.RuntimeValue) (*variable.RuntimeValue, bool, error) {
+	p.enterNewScope()
+	defer p.exitScope()
+
+	for name, val := range bindings {
+		if _, err := p.currentScope().declareVar(name, val); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if arm.Guard != nil {
+		guard, err := p.evaluateValueExpr(arm.Guard)
+		if err != nil {
+			return nil, false, err
+		}
+		ok, isBool := guard.Val.(bool)
+		if !isBool {
+			return nil, false, gg.Runtime("match guard must evaluate to bool, evaluating %s", arm.Guard.Name())
+		}
+		if !ok {
+			return nil, false, nil
+		}
+	}
+
+	switch body := arm.Body.(type) {
+	case gg_ast.BlockStatement:
+		res, err := p.evaluateBlockValue(body)
+		return res, true, err
+	case gg_ast.ValueExpression:
+		res, err := p.evaluateValueExpr(body)
+		return res, true, err
+	}
+	return nil, true, gg.Crit("invalid match arm body: %T", arm.Body)
+}
+
+// reports whether val has the shape of pat, adding the values of the names pat binds to bindings
+func (p *Program) matchPattern(pat gg_ast.Pattern, val *variable.RuntimeValue, bindings map[string]*variable.RuntimeValue) (bool, error) {
+	switch pat := pat.(type) {
+	case *gg_ast.WildcardPattern:
+		return true, nil
+	case *gg_ast.BindingPattern:
+		// the parser makes sure a pattern binds every name once
+		bindings[pat.Name] = val
+		return true, nil
+	case *gg_ast.LiteralPattern:
+		lit, err := p.evaluateValueExpr(pat.Value)
+		if err != nil {
+			return false, err
+		}
+		// values of types that can't be compared to the literal don't match it
+		op, exists := operators.Get("==", val.Typ, lit.Typ)
+		if !exists {
+			return false, nil
+		}
+		eq, err := op.Evaluate(val.Val, lit.Val)
+		if err != nil {
+			return false, err
+		}
+		return eq == true, nil
+	case *gg_ast.ArrayPattern:
+		arr, ok := val.Val.(Array)
+		if !ok {
+			return false, nil
+		}
+		if len(arr) < len(pat.Elements) || !pat.HasRest && len(arr) != len(pat.Elements) {
+			return false, nil
+		}
+		for i, elem := range pat.Elements {
+			matched, err := p.matchPattern(elem, &variable.RuntimeValue{Val: arr[i].Val, Typ: arr[i].Typ}, bindings)
+			if err != nil || !matched {
+				return false, err
+			}
+		}
+		if pat.Rest != "" {
+			rest := &gg_ast.BindingPattern{Span: pat.Span, Name: pat.Rest}
+			return p.matchPattern(rest, &variable.RuntimeValue{Val: append(Array{}, arr[len(pat.Elements):]...), Typ: variable.Array}, bindings)
+		}
+		return true, nil
+	case *gg_ast.ObjectPattern:
+		obj, ok := val.Val.(Object)
+		if !ok {
+			return false, nil
+		}
+		for _, prop := range pat.Properties {
+			propVal, exists := obj.Get(prop.Key)
+			if !exists {
+				return false, nil
+			}
+			matched, err := p.matchPattern(prop.Pattern, propVal, bindings)
+			if err != nil || !matched {
+				return false, err
+			}
+		}
+		return true, nil
+	case *gg_ast.OrPattern:
+		// an alternative that fails halfway may have bound some names already
+		for _, alt := range pat.Alternatives {
+			tried := maps.Clone(bindings)
+			matched, err := p.matchPattern(alt, val, tried)
+			if err != nil {
+				return false, err
+			}
+			if matched {
+				maps.Copy(bindings, tried)
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return false, gg.Crit("invalid pattern: %T", pat)
+}
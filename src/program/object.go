@@ -6,49 +6,52 @@ import (
 	"gg-lang/src/variable"
 )
 
-type Object = map[string]*variable.RuntimeValue
+type Object = *variable.Properties
 
-func (p *Program) getDotAccessAssignmentTarget(expr *gg_ast.DotAccessAssignmentExpression) (Object, error) {
-	e := expr.Target
-	var currentObject Object
-	var res *variable.RuntimeValue
-
-	for i, accessKey := range e.AccessChain[:len(e.AccessChain)-1] {
-		if i == 0 {
-			v := p.findVariable(accessKey)
-			if v == nil {
-				return nil, gg.Runtime("undefined variable: %s\nevaluating %s\n%s", accessKey, e.Name(), gg_ast.NoBuilderExprString(expr))
-			}
-			res = v.RuntimeValue
-		} else {
-			var ok bool
-			res, ok = currentObject[accessKey]
-			if !ok {
-				return nil, gg.Runtime("undefined property: %s\nevaluating %s\n%s", accessKey, e.Name(), gg_ast.NoBuilderExprString(expr))
-			}
-		}
-
-		if res.Typ != variable.Object {
-			return nil, gg.Runtime("%s is not an object, evaluating\n%s", accessKey, gg_ast.NoBuilderExprString(expr))
-		}
-
-		currentObject = res.Val.(Object)
+// evaluates the object a property is accessed on. it's nil for a?.b when a is nil
+func (p *Program) evaluateObject(e *gg_ast.DotAccessExpression) (Object, error) {
+	res, err := p.evaluateValueExpr(e.Object)
+	if err != nil {
+		return nil, err
+	}
+	if e.Optional && res.Typ == variable.Void {
+		return nil, nil
 	}
 
-	return currentObject, nil
+	if res.Typ != variable.Object {
+		return nil, gg.Runtime("%s is not an object, evaluating %s", e.Object.Name(), e.Name())
+	}
+	return res.Val.(Object), nil
 }
 
-func (p *Program) evaluateDotAccessAssignment(expr *gg_ast.DotAccessAssignmentExpression) error {
-	target, err := p.getDotAccessAssignmentTarget(expr)
+// evaluates a property name that's only known at runtime, like k in {[k]: v} or obj[k]
+func (p *Program) evaluateKey(expr gg_ast.ValueExpression) (string, error) {
+	key, err := p.evaluateValueExpr(expr)
 	if err != nil {
-		return err
+		return "", err
+	}
+	name, ok := key.Val.(string)
+	if !ok {
+		return "", gg.Runtime("property names must be strings, %s is %s", expr.Name(), key.Typ.String())
 	}
+	return name, nil
+}
 
-	field := expr.Target.AccessChain[len(expr.Target.AccessChain)-1]
-	val, err := p.evaluateValueExpr(expr.Value)
+func (p *Program) evaluateDotAccess(e *gg_ast.DotAccessExpression) (*variable.RuntimeValue, error) {
+	obj, err := p.evaluateObject(e)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if obj == nil {
+		return &variable.RuntimeValue{Typ: variable.Void}, nil
+	}
+
+	property, exists := obj.Get(e.Property)
+	if !exists {
+		if e.Optional {
+			return &variable.RuntimeValue{Typ: variable.Void}, nil
+		}
+		return nil, gg.Runtime("undefined property: %s in object %s, evaluating %s", e.Property, e.Object.Name(), e.Name())
 	}
-	target[field] = val
-	return nil
+	return property, nil
 }
@@ -14,7 +14,19 @@ type Program struct {
 	scopes *stack.Stack[*Scope]
 	OpMap  *operators.OpMap
 
+	// lets assignment declare the variables it doesn't find, like it did
+	// before let and const. off by default, assigning to an undeclared name is an error.
+	Compat bool
+
 	returnValue *variable.RuntimeValue
+	// set by break and continue until the loop they apply to handles it
+	loopSignal *loopSignal
+}
+
+// a break or continue on its way out to its loop
+type loopSignal struct {
+	isBreak bool
+	label   string // optional
 }
 
 func (p *Program) currentScope() *Scope {
@@ -99,3 +111,25 @@ func (p *Program) runBlockStmtNewScope(block gg_ast.BlockStatement) error {
 	defer p.exitScope()
 	return p.runBlockStmt(block)
 }
+
+// runs a block in a new scope and returns the value of its last expression,
+// or void if the block doesn't end with a value
+func (p *Program) evaluateBlockValue(block gg_ast.BlockStatement) (*variable.RuntimeValue, error) {
+	p.enterNewScope()
+	defer p.exitScope()
+
+	void := &variable.RuntimeValue{Typ: variable.Void}
+	if len(block) == 0 {
+		return void, nil
+	}
+	if err := p.runBlockStmt(block[:len(block)-1]); err != nil {
+		return nil, err
+	}
+
+	last, isValue := block[len(block)-1].(gg_ast.ValueExpression)
+	// a return, break or continue before the end of the block means there's no value
+	if !isValue || p.returnValue != nil || p.loopSignal != nil {
+		return void, p.RunExpression(block[len(block)-1])
+	}
+	return p.evaluateValueExpr(last)
+}
@@ -3,14 +3,25 @@ package program
 import "gg-lang/src/gg_ast"
 
 type RuntimeFunc struct {
-	Name          string
+	Name          string // empty for anonymous routines
 	Decl          *gg_ast.FunctionDeclExpression
 	CapturedScope *Scope
 }
 
+func (rf *RuntimeFunc) String() string {
+	if rf.Name == "" {
+		return "routine (anonymous)"
+	}
+	return "routine " + rf.Name
+}
+
 func NewRuntimeFunc(decl *gg_ast.FunctionDeclExpression, scope *Scope) *RuntimeFunc {
+	name := ""
+	if decl.Target != nil {
+		name = decl.Target.Name()
+	}
 	return &RuntimeFunc{
-		Name:          decl.Target.Name(),
+		Name:          name,
 		Decl:          decl,
 		CapturedScope: scope,
 	}
@@ -49,6 +49,25 @@ func (p *Program) enterNewScope() {
 	p.scopes.Push(ns)
 }
 
+// replaces the current scope with a copy of it, whose variables can change
+// without changing the ones in the scopes closures have captured
+func (p *Program) copyCurrentScope() {
+	prev, _ := p.scopes.Pop()
+	ns := &Scope{
+		Parent:    prev.Parent,
+		variables: make(map[string]*variable.Variable, len(prev.variables)),
+		caller:    prev.caller,
+	}
+	for name, v := range prev.variables {
+		ns.variables[name] = &variable.Variable{
+			Name:         v.Name,
+			RuntimeValue: &variable.RuntimeValue{Val: v.RuntimeValue.Val, Typ: v.RuntimeValue.Typ},
+			Const:        v.Const,
+		}
+	}
+	p.scopes.Push(ns)
+}
+
 func (p *Program) enterCapturedScope(scope *Scope) {
 	p.scopes.Push(scope)
 }
@@ -0,0 +1,95 @@
+package program
+
+import (
+	"fmt"
+	"gg-lang/src/gg"
+	"gg-lang/src/gg_ast"
+	"gg-lang/src/variable"
+)
+
+// evaluates the elements of an array literal or the arguments of a call from left to right,
+// with the elements of spread arrays in place of the spreads. also returns the source of every
+// value, like args or args[1] for an element of a spread array, or "" if it isn't a variable.
+func (p *Program) evaluateList(exprs []gg_ast.ValueExpression) ([]*variable.RuntimeValue, []string, error) {
+	var vals []*variable.RuntimeValue
+	var sources []string
+	for _, expr := range exprs {
+		spread, isSpread := expr.(*gg_ast.SpreadExpression)
+		if !isSpread {
+			val, err := p.evaluateValueExpr(expr)
+			if err != nil {
+				return nil, nil, err
+			}
+			vals = append(vals, val)
+			sources = append(sources, variableName(expr))
+			continue
+		}
+
+		val, err := p.evaluateValueExpr(spread.Value)
+		if err != nil {
+			return nil, nil, err
+		}
+		arr, ok := val.Val.(Array)
+		if !ok {
+			return nil, nil, withExprPos(gg.Runtime("cannot spread %s, only arrays can be spread into arrays and arguments, got %s", spread.Value.Name(), val.Typ.String()), spread)
+		}
+		name := variableName(spread.Value)
+		for i, elem := range arr {
+			vals = append(vals, &variable.RuntimeValue{Val: elem.Val, Typ: elem.Typ})
+			if name == "" {
+				sources = append(sources, "")
+				continue
+			}
+			sources = append(sources, fmt.Sprintf("%s[%d]", name, i))
+		}
+	}
+	return vals, sources, nil
+}
+
+// the name of expr if it's a variable, literals and calls like {} or f() don't name their value
+func variableName(expr gg_ast.ValueExpression) string {
+	if id, ok := expr.(*gg_ast.Identifier); ok && id.Kind() == gg_ast.ExprVariable {
+		return id.Name()
+	}
+	return ""
+}
+
+// evaluates an object literal's properties and spreads from left to right, so later ones win
+func (p *Program) evaluateObjectExpression(expr *gg_ast.ObjectExpression) (*variable.RuntimeValue, error) {
+	obj := variable.NewProperties()
+	for _, prop := range expr.Properties {
+		spread, isSpread := prop.Value.(*gg_ast.SpreadExpression)
+		if !isSpread {
+			key := prop.Key
+			if prop.Computed != nil {
+				var err error
+				if key, err = p.evaluateKey(prop.Computed); err != nil {
+					return nil, err
+				}
+			}
+			value, err := p.evaluateValueExpr(prop.Value)
+			if err != nil {
+				return nil, err
+			}
+			obj.Set(key, value)
+			continue
+		}
+
+		value, err := p.evaluateValueExpr(spread.Value)
+		if err != nil {
+			return nil, err
+		}
+		src, ok := value.Val.(Object)
+		if !ok {
+			return nil, withExprPos(gg.Runtime("cannot spread %s, only objects can be spread into objects, got %s", spread.Value.Name(), value.Typ.String()), spread)
+		}
+		for _, name := range src.Keys() {
+			val, _ := src.Get(name)
+			obj.Set(name, val)
+		}
+	}
+	return &variable.RuntimeValue{
+		Val: obj,
+		Typ: variable.Object,
+	}, nil
+}
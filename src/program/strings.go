@@ -24,7 +24,7 @@ func (l *Length) Call(args ...*variable.RuntimeValue) (*variable.RuntimeValue, e
 	switch args[0].Typ {
 	case variable.String:
 		return &variable.RuntimeValue{
-			Val: len(args[0].Val.(string)),
+			Val: int64(len(args[0].Val.(string))),
 			Typ: variable.Integer,
 		}, nil
 	default:
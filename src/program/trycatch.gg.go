@@ -10,7 +10,7 @@ func (p *Program) evaluateCatchExpression(expr *gg_ast.CatchExpression, thrownEr
 	p.enterNewScope()
 	defer p.exitScope()
 
-	_, err := p.currentScope().declareVar(expr.ErrorParam, &variable.RuntimeValue{Val: thrownErr.Error(), Typ: variable.String})
+	_, err := p.currentScope().declareVar(expr.ErrorParam, &variable.RuntimeValue{Val: thrownErr.Message, Typ: variable.String})
 	if err != nil {
 		return err
 	}
@@ -38,7 +38,7 @@ func (p *Program) evaluateTryCatchExpression(expr *gg_ast.TryCatchExpression) er
 			}
 
 			if finallyBlock != nil {
-				err = p.runBlockStmtNewScope(*finallyBlock)
+				err = p.runFinally(*finallyBlock)
 				if err != nil {
 					return err
 				}
@@ -50,7 +50,7 @@ func (p *Program) evaluateTryCatchExpression(expr *gg_ast.TryCatchExpression) er
 	}
 
 	if finallyBlock != nil {
-		err = p.runBlockStmtNewScope(*finallyBlock)
+		err = p.runFinally(*finallyBlock)
 		if err != nil {
 			return err
 		}
@@ -58,3 +58,19 @@ func (p *Program) evaluateTryCatchExpression(expr *gg_ast.TryCatchExpression) er
 
 	return nil
 }
+
+// the finally block runs even when the try or catch block ended with a return, break or
+// continue, which carries on once the finally block is done
+func (p *Program) runFinally(block gg_ast.BlockStatement) error {
+	returnValue, loopSignal := p.returnValue, p.loopSignal
+	p.returnValue, p.loopSignal = nil, nil
+
+	if err := p.runBlockStmtNewScope(block); err != nil {
+		return err
+	}
+	// a return, break or continue in the finally block replaces the pending one
+	if p.returnValue == nil && p.loopSignal == nil {
+		p.returnValue, p.loopSignal = returnValue, loopSignal
+	}
+	return nil
+}
@@ -6,9 +6,12 @@ import (
 	"gg-lang/src/operators"
 	"gg-lang/src/variable"
 	"strconv"
+	"strings"
 )
 
-func (p *Program) evaluateValueExpr(expr gg_ast.ValueExpression) (*variable.RuntimeValue, error) {
+func (p *Program) evaluateValueExpr(expr gg_ast.ValueExpression) (_ *variable.RuntimeValue, err error) {
+	defer func() { err = withExprPos(err, expr) }()
+
 	switch expr.Kind() {
 	case gg_ast.ExprArrayIndex:
 		expr := expr.(*gg_ast.ArrayIndexExpression)
@@ -27,15 +30,16 @@ func (p *Program) evaluateValueExpr(expr gg_ast.ValueExpression) (*variable.Runt
 		}
 		return nil, gg.Runtime("undefined variable: %s", name)
 	case gg_ast.ExprIntLiteral:
-		name := expr.(*gg_ast.Identifier).Name()
-		intVal, err := strconv.Atoi(name)
-		if err != nil {
-			return nil, gg.Crit("unable to evaluate int literal: %s", err.Error())
-		}
+		// number literals are parsed once by the tokenizer
 		return &variable.RuntimeValue{
-			Val: intVal,
+			Val: expr.(*gg_ast.Identifier).Tok.Value,
 			Typ: variable.Integer,
 		}, nil
+	case gg_ast.ExprFloatLiteral:
+		return &variable.RuntimeValue{
+			Val: expr.(*gg_ast.Identifier).Tok.Value,
+			Typ: variable.Float,
+		}, nil
 	case gg_ast.ExprBoolLiteral:
 		name := expr.(*gg_ast.Identifier).Name()
 		boolVal, err := strconv.ParseBool(name)
@@ -51,6 +55,26 @@ func (p *Program) evaluateValueExpr(expr gg_ast.ValueExpression) (*variable.Runt
 			Val: expr.(*gg_ast.Identifier).Name(),
 			Typ: variable.String,
 		}, nil
+	case gg_ast.ExprNilLiteral:
+		return &variable.RuntimeValue{Typ: variable.Void}, nil
+	case gg_ast.ExprTemplate:
+		e := expr.(*gg_ast.TemplateExpression)
+		sb := &strings.Builder{}
+		for i, s := range e.Strings {
+			sb.WriteString(s)
+			if i == len(e.Exprs) {
+				break
+			}
+			val, err := p.evaluateValueExpr(e.Exprs[i])
+			if err != nil {
+				return nil, err
+			}
+			sb.WriteString(variable.ToString(val.Val))
+		}
+		return &variable.RuntimeValue{
+			Val: sb.String(),
+			Typ: variable.String,
+		}, nil
 	case gg_ast.ExprBinary:
 		binExp := expr.(*gg_ast.BinaryExpression)
 
@@ -58,25 +82,20 @@ func (p *Program) evaluateValueExpr(expr gg_ast.ValueExpression) (*variable.Runt
 		if err != nil {
 			return nil, err
 		}
+		// the right side of ?? is only evaluated when the left side is nil
+		if binExp.Op.Symbol == "??" {
+			if left.Typ != variable.Void {
+				return left, nil
+			}
+			return p.evaluateValueExpr(binExp.Rhs)
+		}
 
 		right, err := p.evaluateValueExpr(binExp.Rhs)
 		if err != nil {
 			return nil, err
 		}
 
-		op, exists := operators.Get(binExp.Op.Symbol, left.Typ, right.Typ)
-		if !exists {
-			return nil, gg.Runtime(
-				"evaluateValueExpr: op %s not supported between types %s and %s\nevaluating: %s", binExp.Op, left.Typ.String(), right.Typ.String(), gg_ast.NoBuilderExprString(expr))
-		}
-
-		value := op.Evaluate(left.Val, right.Val)
-		resultType := op.ResultType()
-
-		return &variable.RuntimeValue{
-			Val: value,
-			Typ: resultType,
-		}, nil
+		return p.applyOperator(binExp.Op.Symbol, left, right, expr)
 	case gg_ast.ExprFunctionCall:
 		f := expr.(*gg_ast.FunctionCallExpression)
 		return p.call(f)
@@ -97,47 +116,56 @@ func (p *Program) evaluateValueExpr(expr gg_ast.ValueExpression) (*variable.Runt
 			return nil, gg.Runtime(
 				"evaluateValueExpr: unary op %s not supported for type %s\nevaluating: %s", e.Op.Symbol, rhs.Typ.String(), gg_ast.NoBuilderExprString(expr))
 		}
-		value := op.Evaluate(rhs.Val)
+		value, err := op.Evaluate(rhs.Val)
+		if err != nil {
+			return nil, err
+		}
 		return &variable.RuntimeValue{
 			Val: value,
 			Typ: rhs.Typ,
 		}, nil
 	case gg_ast.ExprObject:
-		e := expr.(*gg_ast.ObjectExpression)
-		val := make(Object)
-		for name, expr := range e.Properties {
-			value, err := p.evaluateValueExpr(expr)
-			if err != nil {
-				return nil, err
-			}
-			val[name] = value
-		}
-		return &variable.RuntimeValue{
-			Val: val,
-			Typ: variable.Object,
-		}, nil
+		return p.evaluateObjectExpression(expr.(*gg_ast.ObjectExpression))
 	case gg_ast.ExprDotAccess:
-		e := expr.(*gg_ast.DotAccessExpression)
-		v := p.findVariable(e.AccessChain[0])
-		if v == nil {
-			return nil, gg.Runtime("undefined variable: %s\nevaluating %s\n%s", e.AccessChain[0], e.Name(), gg_ast.NoBuilderExprString(expr))
+		return p.evaluateDotAccess(expr.(*gg_ast.DotAccessExpression))
+	case gg_ast.ExprMatch:
+		return p.evaluateMatchExpression(expr.(*gg_ast.MatchExpression))
+	case gg_ast.ExprIfElse:
+		return p.evaluateIfElse(expr.(*gg_ast.IfElseStatement))
+	case gg_ast.ExprConditional:
+		e := expr.(*gg_ast.ConditionalExpression)
+		cond, err := p.evaluateValueExpr(e.Condition)
+		if err != nil {
+			return nil, err
 		}
-		res := v.RuntimeValue
-		if res.Typ != variable.Object {
-			return nil, gg.Runtime("%s is not an object, evaluating\n%s", v.Name, gg_ast.NoBuilderExprString(expr))
+		if _, ok := cond.Val.(bool); !ok {
+			return nil, gg.Runtime("condition of ?: must evaluate to bool, evaluating %s", e.Condition.Name())
 		}
-
-		for _, symbol := range e.AccessChain[1:] {
-			currentPropertyMap := res.Val.(map[string]*variable.RuntimeValue)
-			property, exists := currentPropertyMap[symbol]
-			if !exists {
-				return nil, gg.Runtime("undefined property: %s in object %s\nevaluating %s\n%s", symbol, e.AccessChain[0], e.Name(), gg_ast.NoBuilderExprString(expr))
-			}
-			res = property
+		// only the branch the condition picks is evaluated
+		if cond.Val.(bool) {
+			return p.evaluateValueExpr(e.Then)
 		}
-
-		return res, nil
+		return p.evaluateValueExpr(e.Else)
 	default:
 		return nil, gg.Crit("evaluateValueExpr: invalid expression type: %v", expr)
 	}
 }
+
+// applies a binary operator from the operators table, expr is the expression being evaluated
+func (p *Program) applyOperator(symbol string, left, right *variable.RuntimeValue, expr gg_ast.Expression) (*variable.RuntimeValue, error) {
+	op, exists := operators.Get(symbol, left.Typ, right.Typ)
+	if !exists {
+		return nil, gg.Runtime(
+			"evaluateValueExpr: op %s not supported between types %s and %s\nevaluating: %s", symbol, left.Typ.String(), right.Typ.String(), gg_ast.NoBuilderExprString(expr))
+	}
+
+	value, err := op.Evaluate(left.Val, right.Val)
+	if err != nil {
+		return nil, err
+	}
+
+	return &variable.RuntimeValue{
+		Val: value,
+		Typ: op.ResultType(),
+	}, nil
+}
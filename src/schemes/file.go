@@ -15,27 +15,44 @@ func makeTimestamp() int64 {
 	return time.Now().UnixNano() / 1e6
 }
 
+// Options are the command line flags for running a file
+type Options struct {
+	// let assignment declare variables, see program.Program.Compat
+	Compat bool
+	// save the tokens of the file to out/stmts.json for debugging
+	DumpTokens bool
+}
+
 // execute a GG program from a file and output the AST to a file
-func Exec(filename string) {
+func Exec(filename string, opts Options) {
 	t := makeTimestamp()
 	fmt.Println("Reading file:", filename)
-	out, err := os.ReadFile(filename)
-
-	if err != nil {
-		panic(err)
-	}
+	ast := loadFile(filename, opts)
 
-	// tokenize the input manually so we can save the tokens to a file for debugging
-	stmts, err := token.TokenizeRunes([]rune(string(out)))
+	fmt.Println("Running program...")
+	sess := program.New()
+	sess.Compat = opts.Compat
+	err := sess.Run(ast)
 	gg.Handle(err)
 
-	stmtsJson, err := json.MarshalIndent(stmts, "", "    ")
-	gg.Handle(err)
+	fmt.Println(makeTimestamp()-t, "ms")
+}
 
-	err = os.WriteFile("out/stmts.json", stmtsJson, 0644)
-	gg.Handle(err)
+// builds the AST of a file and saves it to out/ast.json. the file is read as the
+// parser needs it, so large generated scripts are never all in memory as source or tokens.
+func loadFile(filename string, opts Options) *gg_ast.Ast {
+	if opts.DumpTokens {
+		dumpTokens(filename)
+	}
 
-	ast, err := gg_ast.BuildFromTokens(stmts)
+	f, err := os.Open(filename)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	// lexical and syntax errors are reported together
+	ast, err := gg_ast.BuildFromScanner(token.NewFileScanner(filename, f))
 	gg.Handle(err)
 
 	tree, err := json.MarshalIndent(ast, "", "    ")
@@ -45,11 +62,24 @@ func Exec(filename string) {
 	gg.Handle(err)
 
 	fmt.Println("AST saved to out/ast.json")
+	return ast
+}
 
-	fmt.Println("Running program...")
-	sess := program.New()
-	err = sess.Run(ast)
+// tokenizes the file on its own to save the tokens to out/stmts.json.
+// lexical errors are left to the parse that follows.
+func dumpTokens(filename string) {
+	f, err := os.Open(filename)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	stmts, _ := token.Tokenize(token.NewFileScanner(filename, f))
+	stmtsJson, err := json.MarshalIndent(stmts, "", "    ")
 	gg.Handle(err)
 
-	fmt.Println(makeTimestamp()-t, "ms")
+	err = os.WriteFile("out/stmts.json", stmtsJson, 0644)
+	gg.Handle(err)
+
+	fmt.Println("tokens saved to out/stmts.json")
 }
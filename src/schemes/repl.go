@@ -2,26 +2,50 @@ package schemes
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"gg-lang/src/gg_ast"
 	"gg-lang/src/program"
+	"gg-lang/src/token"
+	"io"
 	"os"
 	"strings"
 )
 
-func Repl() {
+func Repl(compat bool) {
 	sess := program.New()
+	sess.Compat = compat
+	input := bufio.NewReader(os.Stdin)
 	fmt.Println("Welcome to the GG programming language!")
+
+	// lines are collected until they form complete statements, so a
+	// routine or block can span several lines
+	src := &strings.Builder{}
 	for {
-		fmt.Print("gg> ")
-		input := bufio.NewScanner(os.Stdin)
-		input.Scan()
+		if src.Len() == 0 {
+			fmt.Print("gg> ")
+		} else {
+			fmt.Print("... ")
+		}
+
+		line, readErr := input.ReadString('\n')
+		src.WriteString(line)
 
-		text := input.Text()
-		text = strings.TrimSpace(text)
+		ast, err := gg_ast.BuildFromScanner(token.NewScanner(strings.NewReader(src.String())))
+		if errors.Is(err, io.ErrUnexpectedEOF) && readErr == nil {
+			continue
+		}
+		src.Reset()
 
-		err := sess.RunString(text)
+		if err == nil {
+			err = sess.Run(ast)
+		}
 		if err != nil {
 			fmt.Printf("Error: %s\n", err)
 		}
+		if readErr != nil {
+			fmt.Println()
+			return
+		}
 	}
 }
@@ -0,0 +1,593 @@
+package token
+
+import (
+	"bufio"
+	"errors"
+	"gg-lang/src/gg"
+	"io"
+	"strconv"
+	"strings"
+	uni "unicode"
+	"unicode/utf8"
+)
+
+// Scanner turns gg source read from an io.Reader into tokens, one at a time.
+// It only holds the few runes it needs to look ahead, so the source never has
+// to be in memory all at once.
+type Scanner struct {
+	r    *bufio.Reader
+	file string
+
+	// runes read from r that haven't been consumed yet, buf[0] is the current rune
+	buf     []rune
+	readErr error
+
+	// the position of buf[0]
+	offset int
+	line   int
+	col    int
+
+	// the start of the token being scanned and every rune consumed since
+	tokStart int
+	tokPos   gg.Pos
+	raw      strings.Builder
+
+	// for every ${ that hasn't been closed yet, how many braces are open inside it
+	interpolations []int
+
+	// every lexical error found so far
+	errs gg.SyntaxErrs
+}
+
+func NewScanner(r io.Reader) *Scanner {
+	return NewFileScanner("", r)
+}
+
+// NewFileScanner is NewScanner for source read from a file. Every token's
+// position will carry the file name.
+func NewFileScanner(filename string, r io.Reader) *Scanner {
+	return &Scanner{
+		r:    bufio.NewReader(r),
+		file: filename,
+		line: 1,
+		col:  1,
+	}
+}
+
+// Next returns the next token in the source. At the end of the source it
+// returns io.EOF, along with a token holding any comments after the last token.
+// Source that isn't valid is returned as an Illegal token and scanning goes on,
+// the errors are collected in Errs.
+func (s *Scanner) Next() (Token, error) {
+	var trivia []Token
+	for {
+		s.begin()
+		r, ok := s.curr()
+		if !ok {
+			if s.readErr != nil {
+				return Token{}, s.readErr
+			}
+			if len(s.interpolations) > 0 {
+				s.interpolations = nil
+				s.report(unexpectedEOF(s.pos(), "unterminated string interpolation, expected '}'"))
+			}
+			eof := s.tok("", 0)
+			eof.Trivia = trivia
+			return eof, io.EOF
+		}
+		next, hasNext := s.peek(1)
+
+		var tok Token
+		var err error
+		switch {
+		case shouldIgnore(r):
+			s.advance()
+			continue
+		case r == '/' && hasNext && next == '/':
+			trivia = append(trivia, s.scanLineComment())
+			continue
+		case r == '/' && hasNext && next == '*':
+			comment, err := s.scanBlockComment()
+			if err != nil {
+				return s.illegal(err)
+			}
+			trivia = append(trivia, comment)
+			continue
+		case isRuneReserved(r, Quote):
+			tok, err = s.scanStringPart(false)
+		case isRuneReserved(r, RawQuote):
+			tok, err = s.scanRawString()
+		case isRuneReserved(r, Dot) && hasNext && uni.IsDigit(next):
+			// a float literal without an integer part, e.g. .5
+			tok, err = s.scanNumber()
+		case isRuneReserved(r, CloseBrace) && s.closesInterpolation():
+			tok, err = s.scanStringPart(true)
+		case s.hasPunctuation():
+			tok = s.scanPunctuation()
+		case uni.IsDigit(r):
+			tok, err = s.scanNumber()
+		case uni.IsLetter(r) || r == '_':
+			tok = s.scanIdentifier()
+		default:
+			s.advance()
+			tok, err = s.illegal(gg.SyntaxAt(s.tokPos, "unexpected character %q", r))
+		}
+		if err != nil {
+			return s.illegal(err)
+		}
+
+		tok.Trivia = trivia
+		return tok, nil
+	}
+}
+
+// Errs returns every lexical error found so far, or nil if there were none
+func (s *Scanner) Errs() error {
+	if len(s.errs) == 0 {
+		return nil
+	}
+	return s.errs
+}
+
+func (s *Scanner) report(err *gg.SyntaxErr) {
+	s.errs = append(s.errs, err)
+}
+
+// reports err and turns everything consumed since begin into an Illegal token.
+// errors that aren't syntax errors, like failing to read, stop the scanner.
+func (s *Scanner) illegal(err error) (Token, error) {
+	var synErr *gg.SyntaxErr
+	if !errors.As(err, &synErr) {
+		return Token{}, err
+	}
+	s.report(synErr)
+	return s.tok(s.raw.String(), Illegal), nil
+}
+
+// returns the rune n runes after the current one, reading more of the source if needed
+func (s *Scanner) peek(n int) (rune, bool) {
+	for len(s.buf) <= n && s.readErr == nil {
+		r, _, err := s.r.ReadRune()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				s.readErr = gg.Crit("reading source: %v", err)
+			}
+			break
+		}
+		s.buf = append(s.buf, r)
+	}
+	if n < len(s.buf) {
+		return s.buf[n], true
+	}
+	return 0, false
+}
+
+func (s *Scanner) curr() (rune, bool) {
+	return s.peek(0)
+}
+
+// true if the current rune is r
+func (s *Scanner) is(r rune) bool {
+	c, ok := s.curr()
+	return ok && c == r
+}
+
+// consumes the current rune, adding it to the raw text of the token being scanned
+func (s *Scanner) advance() {
+	r, ok := s.curr()
+	if !ok {
+		return
+	}
+	s.buf = s.buf[1:]
+	s.raw.WriteRune(r)
+
+	s.offset++
+	if r == '\n' {
+		s.line++
+		s.col = 1
+	} else {
+		s.col++
+	}
+}
+
+func (s *Scanner) pos() gg.Pos {
+	return gg.Pos{File: s.file, Line: s.line, Col: s.col}
+}
+
+// marks the current rune as the start of a new token
+func (s *Scanner) begin() {
+	s.tokStart = s.offset
+	s.tokPos = s.pos()
+	s.raw.Reset()
+}
+
+// builds a token that runs from the last call to begin up to the current rune
+func (s *Scanner) tok(symbol string, tokType Type) Token {
+	return Token{
+		Start:     s.tokStart,
+		End:       s.offset,
+		Pos:       s.tokPos,
+		EndPos:    s.pos(),
+		Symbol:    symbol,
+		TokenType: tokType,
+		Raw:       s.raw.String(),
+	}
+}
+
+// scanners must consume every rune that they add to a token
+func (s *Scanner) scanIdentifier() Token {
+	id := &strings.Builder{}
+	for {
+		r, ok := s.curr()
+		if !ok || !idRune(r) {
+			// not a letter or digit or underscore
+			break
+		}
+		id.WriteRune(r)
+		s.advance()
+	}
+
+	if isReserved(id.String()) {
+		return s.tok(id.String(), lookup(id.String()))
+	}
+	return s.tok(id.String(), Ident)
+}
+
+// number literals are parsed into their value here, once, and stored in Token.Value
+func (s *Scanner) scanNumber() (Token, error) {
+	if s.is('0') {
+		if base, ok := s.peek(1); ok {
+			if prefix, ok := intPrefixes[uni.ToLower(base)]; ok {
+				return s.scanPrefixedInt(prefix.base, prefix.name)
+			}
+		}
+	}
+
+	num := &strings.Builder{}
+	if err := s.scanDigits(num); err != nil {
+		return Token{}, err
+	}
+	tokType := IntLiteral
+
+	// fraction, only when a digit follows the dot so that 1.foo stays a dot access
+	if next, ok := s.peek(1); s.is('.') && ok && uni.IsDigit(next) {
+		num.WriteRune('.')
+		s.advance()
+		if err := s.scanDigits(num); err != nil {
+			return Token{}, err
+		}
+		tokType = FloatLiteral
+	}
+
+	// exponent, e.g. 1e9, 1E+9, 1e-9
+	if s.is('e') || s.is('E') {
+		sign, hasSign := s.peek(1)
+		digit, hasDigit := sign, hasSign
+		if hasSign && (sign == '+' || sign == '-') {
+			digit, hasDigit = s.peek(2)
+		}
+		if hasDigit && uni.IsDigit(digit) {
+			num.WriteRune('e')
+			s.advance()
+			if sign == '+' || sign == '-' {
+				num.WriteRune(sign)
+				s.advance()
+			}
+			if err := s.scanDigits(num); err != nil {
+				return Token{}, err
+			}
+			tokType = FloatLiteral
+		}
+	}
+
+	if tokType == FloatLiteral {
+		val, err := strconv.ParseFloat(num.String(), 64)
+		if err != nil {
+			return Token{}, gg.SyntaxAt(s.tokPos, "invalid float literal %s", s.raw.String())
+		}
+		tok := s.tok(num.String(), FloatLiteral)
+		tok.Value = val
+		return tok, nil
+	}
+
+	return s.intTok(num.String(), 10)
+}
+
+// scans integers written as 0xFF, 0o755 or 0b1010
+func (s *Scanner) scanPrefixedInt(base int, name string) (Token, error) {
+	s.advance() // consume the 0
+	s.advance() // consume the base letter
+
+	// consume everything that could be part of the literal, so that 0b102 is
+	// reported as a bad binary digit instead of lexing as 0b10 followed by 2
+	num := &strings.Builder{}
+	for {
+		r, ok := s.curr()
+		if !ok || !idRune(r) {
+			break
+		}
+		num.WriteRune(r)
+		s.advance()
+	}
+
+	if num.Len() == 0 {
+		return Token{}, gg.SyntaxAt(s.tokPos, "%s literal has no digits", name)
+	}
+	for _, r := range num.String() {
+		if r == '_' {
+			continue
+		}
+		if d, ok := digitVal(r); !ok || d >= base {
+			return Token{}, gg.SyntaxAt(s.tokPos, "invalid digit '%s' in %s literal %s", string(r), name, s.raw.String())
+		}
+	}
+	digits, err := s.removeSeparators(num.String())
+	if err != nil {
+		return Token{}, err
+	}
+
+	return s.intTok(digits, base)
+}
+
+// builds an IntLiteral token from digits in the given base, whose Symbol is the decimal value
+func (s *Scanner) intTok(num string, base int) (Token, error) {
+	val, err := strconv.ParseInt(num, base, 64)
+	if err != nil {
+		return Token{}, gg.SyntaxAt(s.tokPos, "integer literal %s does not fit in a 64-bit integer", s.raw.String())
+	}
+	tok := s.tok(strconv.FormatInt(val, 10), IntLiteral)
+	tok.Value = val
+	return tok, nil
+}
+
+// consumes a run of decimal digits, which may be separated by underscores, into num
+func (s *Scanner) scanDigits(num *strings.Builder) error {
+	digits := &strings.Builder{}
+	for {
+		r, ok := s.curr()
+		if !ok || !(uni.IsDigit(r) || r == '_') {
+			break
+		}
+		digits.WriteRune(r)
+		s.advance()
+	}
+
+	res, err := s.removeSeparators(digits.String())
+	if err != nil {
+		return err
+	}
+	num.WriteString(res)
+	return nil
+}
+
+// underscores may only appear between two digits, as in 1_000_000
+func (s *Scanner) removeSeparators(num string) (string, error) {
+	if strings.HasPrefix(num, "_") || strings.HasSuffix(num, "_") || strings.Contains(num, "__") {
+		return "", gg.SyntaxAt(s.tokPos, "'_' must separate successive digits in number literal %s", s.raw.String())
+	}
+	return strings.ReplaceAll(num, "_", ""), nil
+}
+
+// returns the longest operator, separator or container starting at the current rune
+func (s *Scanner) matchPunctuation() (string, Type, bool) {
+	runes := make([]rune, 0, maxPunctuationLen)
+	for i := 0; i < maxPunctuationLen; i++ {
+		r, ok := s.peek(i)
+		if !ok {
+			break
+		}
+		runes = append(runes, r)
+	}
+
+	for n := len(runes); n > 0; n-- {
+		sym := string(runes[:n])
+		tokType, ok := punctuation[sym]
+		if !ok {
+			continue
+		}
+		// like in JS, c ?.5 : 1 is c ? .5 : 1, optional chaining never starts a number
+		if next, ok := s.peek(n); tokType == OptionalChain && ok && uni.IsDigit(next) {
+			continue
+		}
+		return sym, tokType, true
+	}
+	return "", 0, false
+}
+
+func (s *Scanner) hasPunctuation() bool {
+	_, _, ok := s.matchPunctuation()
+	return ok
+}
+
+// maximal munch, so x=-1 is x = - 1 while a==b is a == b
+func (s *Scanner) scanPunctuation() Token {
+	sym, tokType, _ := s.matchPunctuation()
+	for range []rune(sym) {
+		s.advance()
+	}
+
+	if n := len(s.interpolations); n > 0 {
+		switch tokType {
+		case OpenBrace:
+			s.interpolations[n-1]++
+		case CloseBrace:
+			s.interpolations[n-1]--
+		}
+	}
+	return s.tok(sym, tokType)
+}
+
+// true if the current closing brace ends a ${ interpolation rather than a block or object
+func (s *Scanner) closesInterpolation() bool {
+	n := len(s.interpolations)
+	return n > 0 && s.interpolations[n-1] == 0
+}
+
+// scans a string up to its closing quote or the next ${. The scanner must be on the
+// opening quote, or on the closing brace of an interpolation when continuing a template.
+func (s *Scanner) scanStringPart(continuing bool) (Token, error) {
+	if continuing {
+		s.interpolations = s.interpolations[:len(s.interpolations)-1]
+	}
+	s.advance() // consume opening quote or closing brace
+
+	str := &strings.Builder{}
+	for {
+		r, ok := s.curr()
+		if !ok {
+			return Token{}, unexpectedEOF(s.tokPos, "unterminated string literal")
+		}
+		if isRuneReserved(r, Quote) {
+			s.advance() // consume closing quote
+			if continuing {
+				return s.tok(str.String(), TemplateTail), nil
+			}
+			return s.tok(str.String(), StringLiteral), nil
+		}
+		if next, ok := s.peek(1); r == '$' && ok && next == '{' {
+			s.advance()
+			s.advance()
+			s.interpolations = append(s.interpolations, 0)
+			if continuing {
+				return s.tok(str.String(), TemplateMiddle), nil
+			}
+			return s.tok(str.String(), TemplateHead), nil
+		}
+		if r == '\\' {
+			// a bad escape doesn't end the string, so the rest of it is still checked
+			esc, err := s.scanEscape()
+			if err != nil {
+				s.report(err)
+				continue
+			}
+			str.WriteRune(esc)
+			continue
+		}
+
+		str.WriteRune(r)
+		s.advance()
+	}
+}
+
+// scans an escape sequence starting at the backslash and returns the rune it stands for
+func (s *Scanner) scanEscape() (rune, *gg.SyntaxErr) {
+	start := s.pos()
+	s.advance() // consume the backslash
+	r, ok := s.curr()
+	if !ok {
+		return 0, unexpectedEOF(start, "unterminated escape sequence")
+	}
+
+	if esc, ok := simpleEscapes[r]; ok {
+		s.advance()
+		return esc, nil
+	}
+	if r != 'u' {
+		return 0, gg.SyntaxAt(start, "unknown escape sequence \\%s", string(r))
+	}
+
+	// unicode escapes are written as \u{1F600}
+	s.advance()
+	if !s.is('{') {
+		return 0, gg.SyntaxAt(start, "expected '{' after \\u in unicode escape sequence")
+	}
+	s.advance()
+
+	hex := &strings.Builder{}
+	for {
+		r, ok := s.curr()
+		if !ok || r == '}' || r == '"' {
+			break
+		}
+		hex.WriteRune(r)
+		s.advance()
+	}
+	if !s.is('}') {
+		return 0, gg.SyntaxAt(start, "expected '}' to close unicode escape sequence")
+	}
+	s.advance()
+
+	if hex.Len() == 0 || hex.Len() > 6 {
+		return 0, gg.SyntaxAt(start, "unicode escape sequence must have 1 to 6 hex digits, got \"%s\"", hex.String())
+	}
+	code, err := strconv.ParseUint(hex.String(), 16, 32)
+	if err != nil {
+		return 0, gg.SyntaxAt(start, "invalid hex digits \"%s\" in unicode escape sequence", hex.String())
+	}
+	if !utf8.ValidRune(rune(code)) {
+		return 0, gg.SyntaxAt(start, "unicode escape sequence \\u{%s} is not a valid code point", hex.String())
+	}
+
+	return rune(code), nil
+}
+
+// raw strings are wrapped in backticks, can span lines and have no escape sequences
+func (s *Scanner) scanRawString() (Token, error) {
+	s.advance() // consume opening backtick
+
+	str := &strings.Builder{}
+	for {
+		r, ok := s.curr()
+		if !ok {
+			return Token{}, unexpectedEOF(s.tokPos, "unterminated raw string literal")
+		}
+		if isRuneReserved(r, RawQuote) {
+			s.advance() // consume closing backtick
+			break
+		}
+
+		str.WriteRune(r)
+		s.advance()
+	}
+
+	return s.tok(str.String(), StringLiteral), nil
+}
+
+// a line comment runs from // up to, but not including, the end of the line
+func (s *Scanner) scanLineComment() Token {
+	for {
+		r, ok := s.curr()
+		if !ok || r == '\n' {
+			break
+		}
+		s.advance()
+	}
+
+	return s.tok(s.raw.String(), LineComment)
+}
+
+// block comments run from /* to the matching */ and may be nested
+func (s *Scanner) scanBlockComment() (Token, error) {
+	depth := 0
+	for {
+		r, ok := s.curr()
+		if !ok {
+			return Token{}, unexpectedEOF(s.tokPos, "unterminated block comment")
+		}
+		next, _ := s.peek(1)
+
+		switch {
+		case r == '/' && next == '*':
+			depth++
+		case r == '*' && next == '/':
+			depth--
+		default:
+			s.advance()
+			continue
+		}
+
+		// both runes of the delimiter belong to the comment
+		s.advance()
+		s.advance()
+		if depth == 0 {
+			return s.tok(s.raw.String(), BlockComment), nil
+		}
+	}
+}
+
+// an error that more source could fix, like an unterminated string
+func unexpectedEOF(pos gg.Pos, msg string) *gg.SyntaxErr {
+	err := gg.SyntaxAt(pos, msg)
+	err.Err = io.ErrUnexpectedEOF
+	return err
+}
@@ -1,6 +1,9 @@
 package token
 
-import "fmt"
+import (
+	"fmt"
+	"gg-lang/src/gg"
+)
 
 type Type int
 
@@ -10,8 +13,14 @@ const (
 	Minus
 	Mul
 	Div
+	Mod
+	Pow
 	BitwiseAnd
 	BitwiseOr
+	BitwiseXor
+	BitwiseNot
+	ShiftLeft
+	ShiftRight
 	LogicalNot
 	LogicalAnd
 	LogicalOr
@@ -21,7 +30,15 @@ const (
 	LessThanEqual
 	GreaterThan
 	GreaterThanEqual
+	NullCoalesce
 	Assign
+	PlusAssign
+	MinusAssign
+	MulAssign
+	DivAssign
+	ModAssign
+	Increment
+	Decrement
 	endOperators
 
 	beginContainers
@@ -32,6 +49,7 @@ const (
 	OpenBracket
 	CloseBracket
 	Quote
+	RawQuote
 	endContainers
 
 	beginSeparators
@@ -39,26 +57,54 @@ const (
 	Comma
 	Dot
 	Colon
+	Question
+	OptionalChain
+	Arrow
+	Range
+	Ellipsis
 	endSeparators
 
 	beginIdentifiers
 	Ident
 	IntLiteral
+	FloatLiteral
 	StringLiteral
+	// "a ${b} c ${d} e" is split into the parts TemplateHead("a "), b,
+	// TemplateMiddle(" c "), d and TemplateTail(" e")
+	TemplateHead
+	TemplateMiddle
+	TemplateTail
 	TrueLiteral
 	FalseLiteral
+	NilLiteral
 	endIdentifiers
 
 	beginKeywords
 	Function
 	For
+	In
 	If
 	Else
 	Return
 	Try
 	Catch
 	Finally
+	Break
+	Continue
+	Match
+	Let
+	Const
 	endKeywords
+
+	// comments never reach the token list on their own,
+	// they are attached to the next token as trivia
+	beginTrivia
+	LineComment
+	BlockComment
+	endTrivia
+
+	// source the scanner could not make sense of, the reason is in Scanner.Errs
+	Illegal
 )
 
 func (t Type) IsOperator() bool {
@@ -73,25 +119,57 @@ func (t Type) IsSeparator() bool {
 func (t Type) IsIdentifier() bool {
 	return t > beginIdentifiers && t < endIdentifiers
 }
+func (t Type) IsTrivia() bool {
+	return t > beginTrivia && t < endTrivia
+}
 func (t Type) IsMathOperator() bool {
-	return t == Plus || t == Minus || t == Mul || t == Div
+	return t == Plus || t == Minus || t == Mul || t == Div || t == Mod || t == Pow
+}
+
+// reports whether tokens of type t always have the same symbol, like '}' or 'routine'
+func (t Type) HasSymbol() bool {
+	_, ok := reservedTokens[t]
+	return ok
 }
 
 func (t Type) String() string {
 	if s, ok := reservedTokens[t]; ok {
 		return s
 	}
+	if s, ok := typeNames[t]; ok {
+		return s
+	}
 	return fmt.Sprintf("TokenType(%d)", t)
 }
 
+// names for the types that have no fixed symbol
+var typeNames = map[Type]string{
+	Ident:          "identifier",
+	IntLiteral:     "integer literal",
+	FloatLiteral:   "float literal",
+	StringLiteral:  "string literal",
+	TemplateHead:   "string interpolation",
+	TemplateMiddle: "string interpolation",
+	TemplateTail:   "string interpolation",
+	LineComment:    "comment",
+	BlockComment:   "comment",
+	Illegal:        "illegal token",
+}
+
 var reservedTokens = map[Type]string{
 	// operators
 	Plus:             "+",
 	Minus:            "-",
 	Mul:              "*",
 	Div:              "/",
+	Mod:              "%",
+	Pow:              "**",
 	BitwiseAnd:       "&",
 	BitwiseOr:        "|",
+	BitwiseXor:       "^",
+	BitwiseNot:       "~",
+	ShiftLeft:        "<<",
+	ShiftRight:       ">>",
 	LogicalNot:       "!",
 	LogicalAnd:       "&&",
 	LogicalOr:        "||",
@@ -101,7 +179,15 @@ var reservedTokens = map[Type]string{
 	LessThanEqual:    "<=",
 	GreaterThan:      ">",
 	GreaterThanEqual: ">=",
+	NullCoalesce:     "??",
 	Assign:           "=",
+	PlusAssign:       "+=",
+	MinusAssign:      "-=",
+	MulAssign:        "*=",
+	DivAssign:        "/=",
+	ModAssign:        "%=",
+	Increment:        "++",
+	Decrement:        "--",
 
 	// terminators
 	Term: ";",
@@ -114,32 +200,55 @@ var reservedTokens = map[Type]string{
 	OpenBracket:  "[",
 	CloseBracket: "]",
 	Quote:        "\"",
+	RawQuote:     "`",
 
 	// separators
-	Comma: ",",
-	Dot:   ".",
-	Colon: ":",
+	Comma:         ",",
+	Dot:           ".",
+	Colon:         ":",
+	Question:      "?",
+	OptionalChain: "?.",
+	Arrow:         "=>",
+	Range:         "..",
+	Ellipsis:      "...",
 
 	// built-in literals
 	TrueLiteral:  "true",
 	FalseLiteral: "false",
+	NilLiteral:   "nil",
 
 	// keyword
 	Function: "routine",
 	For:      "for",
+	In:       "in",
 	If:       "if",
 	Else:     "else",
 	Return:   "return",
 	Try:      "try",
 	Catch:    "catch",
 	Finally:  "finally",
+	Break:    "break",
+	Continue: "continue",
+	Match:    "match",
+	Let:      "let",
+	Const:    "const",
 }
 
 var reservedTokensMap = map[string]Type{}
 
+// every operator, separator and container except quotes, by symbol.
+// the tokenizer always takes the longest symbol that matches the input.
+var punctuation = map[string]Type{}
+var maxPunctuationLen = 0
+
 func init() {
 	for i, c := range reservedTokens {
 		reservedTokensMap[c] = i
+
+		if (i.IsOperator() || i.IsSeparator() || i.IsContainer()) && i != Quote && i != RawQuote {
+			punctuation[c] = i
+			maxPunctuationLen = max(maxPunctuationLen, len([]rune(c)))
+		}
 	}
 }
 
@@ -158,12 +267,27 @@ func lookup(in string) Type {
 }
 
 type Token struct {
-	Start     int `json:"-"`
-	End       int `json:"-"`
+	// rune offsets of the first rune and one past the last rune of the token
+	Start int
+	End   int
+	// source positions of the first rune and one past the last rune of the token
+	Pos    gg.Pos
+	EndPos gg.Pos
+
+	// the value of the token, e.g. a string literal with its escapes resolved
 	Symbol    string
 	TokenType Type
+	// the token exactly as it appears in the source
+	Raw string
+	// the parsed value of number literals, an int64 or a float64
+	Value interface{} `json:",omitempty"`
+
+	// the comments between the previous token and this one, in source order
+	Trivia []Token `json:",omitempty"`
+	// only set on the last token of the input, the comments that follow it
+	TrailingTrivia []Token `json:",omitempty"`
 }
 
 func (t Token) String() string {
-	return fmt.Sprintf("(%d-%d) %s", t.Start, t.End, t.Symbol)
+	return fmt.Sprintf("(%s) %s", t.Pos, t.Symbol)
 }
@@ -1,255 +1,71 @@
 package token
 
 import (
-	"gg-lang/src/gg"
-	"gg-lang/src/parser"
+	"errors"
+	"io"
+	"strings"
 	uni "unicode"
 )
 
-func tokenize(par *parser.Parser[rune]) ([]Token, error) {
-	tk := &tkzr{
-		Par: par,
-	}
+// Tokenize reads every token from the scanner. Comments after the last token
+// are attached to it as TrailingTrivia. If the source has lexical errors the
+// tokens are returned along with gg.SyntaxErrs listing all of them.
+func Tokenize(s *Scanner) ([]Token, error) {
 	var toks []Token
-	a := func(tok Token) {
-		toks = append(toks, tok)
-	}
-	for tk.Par.HasCurr {
-		switch {
-		case shouldIgnore(par.Curr):
-			tk.Par.Advance()
-		case isRuneReserved(tk.Par.Curr, Term):
-			a(tk.parseReservedSingleRuneTok(Term))
-		case isRuneReserved(tk.Par.Curr, Quote):
-			strTok, err := parseStringLiteral(par)
-			if err != nil {
-				return nil, err
-			}
-			a(strTok)
-		case isReserved(string(par.Curr)) && lookup(string(par.Curr)).IsOperator():
-			tok, err := parseOperator(par)
-			if err != nil {
-				return nil, err
-			}
-			a(tok)
-		case isRuneReserved(tk.Par.Curr, OpenBrace):
-			a(tk.parseReservedSingleRuneTok(OpenBrace))
-		case isRuneReserved(tk.Par.Curr, CloseBrace):
-			a(tk.parseReservedSingleRuneTok(CloseBrace))
-		case isRuneReserved(tk.Par.Curr, Colon):
-			a(tk.parseReservedSingleRuneTok(Colon))
-		case isRuneReserved(tk.Par.Curr, Comma):
-			a(tk.parseReservedSingleRuneTok(Comma))
-		case isRuneReserved(tk.Par.Curr, Dot):
-			a(tk.parseReservedSingleRuneTok(Dot))
-		case isRuneReserved(tk.Par.Curr, OpenParen):
-			a(tk.parseReservedSingleRuneTok(OpenParen))
-		case isRuneReserved(tk.Par.Curr, CloseParen):
-			a(tk.parseReservedSingleRuneTok(CloseParen))
-		case isRuneReserved(tk.Par.Curr, OpenBracket):
-			a(tk.parseReservedSingleRuneTok(OpenBracket))
-		case isRuneReserved(tk.Par.Curr, CloseBracket):
-			a(tk.parseReservedSingleRuneTok(CloseBracket))
-		case uni.IsDigit(par.Curr):
-			numTok, err := parseNumLiteral(par)
-			if err != nil {
-				return nil, err
-			}
-			a(numTok)
-		case uni.IsLetter(par.Curr):
-			idTok, err := parseIdentifier(par)
-			if err != nil {
-				return nil, err
+	for {
+		tok, err := s.Next()
+		if errors.Is(err, io.EOF) {
+			if len(toks) > 0 {
+				toks[len(toks)-1].TrailingTrivia = tok.Trivia
 			}
-			a(idTok)
-		default:
-			return nil, gg.Crit("unexpected character%s\n%s", string(par.Curr), par.String())
+			return toks, s.Errs()
 		}
+		if err != nil {
+			return nil, err
+		}
+		toks = append(toks, tok)
 	}
-
-	if len(toks) == 0 {
-		return nil, nil
-	}
-	return toks, nil
-}
-
-type tkzr struct {
-	Par *parser.Parser[rune]
 }
 
 func TokenizeRunes(ins []rune) ([]Token, error) {
-	par := parser.New(ins)
-	par.SetStringer(func(in rune) string {
-		return string(in)
-	})
-	par.SetSeparator("")
-
-	return tokenize(par)
+	return TokenizeFile("", ins)
 }
 
-func (t *tkzr) parseReservedSingleRuneTok(tokType Type) Token {
-	curr := t.Par.Curr
-	ret := Token{
-		Start:     t.Par.Index(),
-		End:       t.Par.Index() + 1,
-		Symbol:    string(curr),
-		TokenType: tokType,
-	}
-
-	t.Par.Advance()
-	return ret
+// TokenizeFile is TokenizeRunes for source read from a file. Every
+// token's position will carry the file name.
+func TokenizeFile(filename string, ins []rune) ([]Token, error) {
+	return Tokenize(NewFileScanner(filename, strings.NewReader(string(ins))))
 }
 
-// parsers must consume every rune that they add to a token
-func parseIdentifier(p *parser.Parser[rune]) (Token, error) {
-	start := p.Index()
-	if !p.HasCurr {
-		return Token{}, gg.Crit("identifier parser called with nothing in parser\n%s", p.String())
-	}
-
-	if !uni.IsLetter(p.Curr) {
-		return Token{}, gg.Crit("expected letter, got %s\n%s", string(p.Curr), p.String())
-	}
-
-	id := ""
-	for {
-		if p.HasCurr && idRune(p.Curr) {
-			id += string(p.Curr)
-			p.Advance()
-			continue
-		}
-		// not a letter or digit or underscore
-		break
-	}
-
-	if id == "" {
-		return Token{}, gg.Crit("could not parse identifier\n%s", p.String())
-	}
-
-	if isReserved(id) {
-		tt := lookup(id)
-		return Token{
-			Start:     start,
-			End:       p.Index() + 1,
-			Symbol:    id,
-			TokenType: tt,
-		}, nil
-	}
-
-	return Token{
-		Start:     start,
-		End:       p.Index() + 1,
-		Symbol:    id,
-		TokenType: Ident,
-	}, nil
-}
-
-func parseNumLiteral(p *parser.Parser[rune]) (Token, error) {
-	start := p.Index()
-	if !p.HasCurr {
-		return Token{}, gg.Crit("number parser called with nothing in parser\n%s", p.String())
-	}
-
-	num := ""
-
-	for {
-		if p.HasCurr && uni.IsDigit(p.Curr) {
-			num += string(p.Curr)
-			p.Advance()
-			continue
-		}
-		// not a digit
-		break
-	}
-
-	if num == "" {
-		return Token{}, gg.Crit("could not parse number\n%s", p.String())
-	}
-
-	return Token{
-		Start:     start,
-		End:       p.Index() + 1,
-		Symbol:    num,
-		TokenType: IntLiteral,
-	}, nil
+var intPrefixes = map[rune]struct {
+	base int
+	name string
+}{
+	'x': {16, "hexadecimal"},
+	'o': {8, "octal"},
+	'b': {2, "binary"},
 }
 
-func parseOperator(p *parser.Parser[rune]) (Token, error) {
-	start := p.Index()
-	if !p.HasCurr {
-		return Token{}, gg.Crit("operator parser called with nothing in parser\n%s", p.String())
-	}
-	op := ""
-
-	for {
-		if p.HasCurr {
-			if isReserved(string(p.Curr)) && lookup(string(p.Curr)).IsOperator() {
-				// token is an operator, add it to the operator string
-				op += string(p.Curr)
-				p.Advance()
-				continue
-			}
-			// not reserved or not an operator, done parsing
-			break
-		} else {
-			// nothing left in parser
-			break
-		}
-	}
-
-	if op == "" {
-		return Token{}, gg.Crit("could not parse operator\n%s", p.String())
-	}
-
-	if isReserved(op) {
-		realOp := lookup(op)
-		if !realOp.IsOperator() {
-			return Token{}, gg.Runtime("unknown operator \n%s", p.String())
-		}
-
-		return Token{
-			Start:     start,
-			End:       p.Index() + 1,
-			Symbol:    op,
-			TokenType: realOp,
-		}, nil
-	}
-
-	return Token{}, gg.Runtime("unknown operator \n%s", p.String())
+var simpleEscapes = map[rune]rune{
+	'"':  '"',
+	'\\': '\\',
+	'n':  '\n',
+	't':  '\t',
+	'r':  '\r',
+	'0':  0,
+	'$':  '$',
 }
 
-func parseStringLiteral(p *parser.Parser[rune]) (Token, error) {
-	if !p.HasCurr {
-		return Token{}, gg.Crit("string literal parser called with nothing in parser\n%s", p.String())
-	}
-	if string(p.Curr) != reservedTokens[Quote] {
-		return Token{}, gg.Crit("string literal parser called on non-quote\n%s", p.String())
+func digitVal(r rune) (int, bool) {
+	switch {
+	case '0' <= r && r <= '9':
+		return int(r - '0'), true
+	case 'a' <= r && r <= 'z':
+		return int(r-'a') + 10, true
+	case 'A' <= r && r <= 'Z':
+		return int(r-'A') + 10, true
 	}
-
-	p.Advance() // consume opening quote
-	start := p.Index()
-
-	str := ""
-
-	for {
-		if !p.HasCurr {
-			return Token{}, gg.Runtime("unterminated string literal\n%s", p.String())
-		}
-		if string(p.Curr) == reservedTokens[Quote] {
-			p.Advance() // consume closing quote
-			break
-		}
-
-		str += string(p.Curr)
-		p.Advance()
-	}
-
-	return Token{
-		Start:     start,
-		End:       p.Index() + 1,
-		Symbol:    str,
-		TokenType: StringLiteral,
-	}, nil
+	return 0, false
 }
 
 func shouldIgnore(curr rune) bool {
@@ -257,7 +73,7 @@ func shouldIgnore(curr rune) bool {
 }
 
 // this checks runes with index in identifier > 0,
-// the first rune is always a letter at this point
+// the first rune is always a letter or an underscore at this point
 func idRune(r rune) bool {
 	return uni.IsLetter(r) || uni.IsDigit(r) || r == '_'
 }
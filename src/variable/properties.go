@@ -0,0 +1,33 @@
+package variable
+
+// Properties are the properties of an object, in the order they were first set
+type Properties struct {
+	keys   []string
+	values map[string]*RuntimeValue
+}
+
+func NewProperties() *Properties {
+	return &Properties{values: make(map[string]*RuntimeValue)}
+}
+
+func (p *Properties) Get(key string) (*RuntimeValue, bool) {
+	val, ok := p.values[key]
+	return val, ok
+}
+
+// sets the property called key, a property that's already set keeps its place in the order
+func (p *Properties) Set(key string, val *RuntimeValue) {
+	if _, exists := p.values[key]; !exists {
+		p.keys = append(p.keys, key)
+	}
+	p.values[key] = val
+}
+
+// the names of the properties, in order
+func (p *Properties) Keys() []string {
+	return append([]string(nil), p.keys...)
+}
+
+func (p *Properties) Len() int {
+	return len(p.keys)
+}
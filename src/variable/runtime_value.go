@@ -1,8 +1,11 @@
 package variable
 
 import (
+	"fmt"
 	"gg-lang/src/gg"
 	"strconv"
+	"strings"
+	"unicode"
 )
 
 type RuntimeValue struct {
@@ -12,9 +15,15 @@ type RuntimeValue struct {
 
 // if error is nil, return val is guaranteed to be of type targetType
 func CoerceTo(val interface{}, targetType VarType) (interface{}, error) {
+	if targetType == String {
+		return ToString(val), nil
+	}
+
 	switch val.(type) {
-	case int:
-		return CoerceFromInt(val.(int), targetType)
+	case int64:
+		return CoerceFromInt(val.(int64), targetType)
+	case float64:
+		return CoerceFromFloat(val.(float64), targetType)
 	case bool:
 		return CoerceFromBool(val.(bool), targetType)
 	default:
@@ -29,17 +38,95 @@ func CoerceFromBool(val bool, targetType VarType) (interface{}, error) {
 	case Boolean:
 		return val, nil
 	default:
-		return nil, gg.Runtime("failed to coerce bool %d to %s", val, targetType.String())
+		return nil, gg.Runtime("failed to coerce bool %t to %s", val, targetType.String())
 	}
 }
-func CoerceFromInt(val int, targetType VarType) (interface{}, error) {
+func CoerceFromInt(val int64, targetType VarType) (interface{}, error) {
 	switch targetType {
 	case String:
-		ret := strconv.Itoa(val)
+		ret := strconv.FormatInt(val, 10)
 		return ret, nil
 	case Integer:
 		return val, nil
+	case Float:
+		return float64(val), nil
 	default:
 		return nil, gg.Runtime("failed to coerce integer %d to %s", val, targetType.String())
 	}
 }
+func CoerceFromFloat(val float64, targetType VarType) (interface{}, error) {
+	switch targetType {
+	case String:
+		return FormatFloat(val), nil
+	case Float:
+		return val, nil
+	default:
+		return nil, gg.Runtime("failed to coerce float %g to %s", val, targetType.String())
+	}
+}
+
+// ToString is how every value is turned into text, whether it's being
+// concatenated to a string, interpolated into a template or printed
+func ToString(val interface{}) string {
+	return toString(val, false)
+}
+
+// strings nested in arrays and objects are quoted, so ["1"] doesn't read as [1]
+func toString(val interface{}, nested bool) string {
+	switch v := val.(type) {
+	case nil:
+		return "nil"
+	case string:
+		if nested {
+			return strconv.Quote(v)
+		}
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return FormatFloat(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case []RuntimeValue:
+		elems := make([]string, len(v))
+		for i, elem := range v {
+			elems[i] = toString(elem.Val, true)
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	case *Properties:
+		props := make([]string, 0, v.Len())
+		for _, key := range v.keys {
+			// keys that aren't names are quoted, like they have to be in object literals
+			name := key
+			if !isName(key) {
+				name = strconv.Quote(key)
+			}
+			props = append(props, name+": "+toString(v.values[key].Val, true))
+		}
+		return "{" + strings.Join(props, ", ") + "}"
+	case fmt.Stringer:
+		return v.String()
+	case interface{ Name() string }:
+		return "builtin " + v.Name()
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func isName(s string) bool {
+	for i, r := range s {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// formats a float so that it always reads as one, 3.0 is "3.0" rather than "3"
+func FormatFloat(val float64) string {
+	ret := strconv.FormatFloat(val, 'g', -1, 64)
+	if strings.ContainsAny(ret, ".eIN") {
+		return ret
+	}
+	return ret + ".0"
+}
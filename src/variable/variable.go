@@ -4,8 +4,10 @@ package variable
 type VarType int
 
 const (
-	// Integer represents a whole number
+	// Integer represents a 64-bit whole number
 	Integer VarType = iota
+	// Float represents a 64-bit floating-point number
+	Float
 	// String represents a sequence of characters
 	String
 	// Boolean represents true and false values
@@ -25,4 +27,6 @@ const (
 type Variable struct {
 	Name         string
 	RuntimeValue *RuntimeValue
+	// declared with const, it can't be assigned to
+	Const bool
 }